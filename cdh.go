@@ -0,0 +1,103 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// cdhBase returns h * (otherX, otherY), which is in the subgroup of order n.
+func cdhBase(otherX, otherY *big.Int, curve elliptic.Curve, h *big.Int) (*big.Int, *big.Int) {
+	if h.Cmp(one) == 0 {
+		return otherX, otherY
+	}
+	return curve.ScalarMult(otherX, otherY, h.Bytes())
+}
+
+// CDH implements the ECC CDH primitive (cofactor Diffie-Hellman) that
+// calculates the shared secret P = h * d * Q from the own private key d and
+// the other party's public key Q, see section 5.7.1.2 of SP 800-56A Rev. 3.
+// h is the cofactor of the elliptic curve, which is taken from the registry
+// (see LookupCurve). The public key of the other party is not validated by
+// this primitive, see ValidatePublicKeyFull and ValidatePublicKeyPartial.
+// Only coordinates outside of the range [0, p-1] are rejected with
+// ErrInvalidPublicKey. ErrIdentity is returned if P is the point at infinity.
+func CDH(ownPriv []byte, otherX, otherY *big.Int, curve elliptic.Curve) (*big.Int, *big.Int, error) {
+	info, err := LookupCurve(curve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up curve: %w", err)
+	}
+	curve = info.Curve
+	if err := checkRange(curve, otherX, otherY); err != nil {
+		return nil, nil, err
+	}
+
+	bx, by := cdhBase(otherX, otherY, curve, info.Cofactor)
+	x, y := scalarMult(curve, bx, by, ownPriv)
+	if isInfinity(x, y) {
+		return nil, nil, ErrIdentity
+	}
+	return x, y, nil
+}
+
+// BlindCDH implements the ECC CDH primitive with the blinded scalar
+// multiplication of ScalarMultBlind. Additional countermeasures can be
+// selected by modes. Like CDH, it returns ErrInvalidPublicKey if a
+// coordinate is out of range and ErrIdentity if the shared secret is the
+// point at infinity.
+func BlindCDH(ownPriv []byte, otherX, otherY *big.Int, curve elliptic.Curve, rand io.Reader, modes ...BlindMode) (*big.Int, *big.Int, error) {
+	info, err := LookupCurve(curve)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to look up curve: %w", err)
+	}
+	curve = info.Curve
+	if err := checkRange(curve, otherX, otherY); err != nil {
+		return nil, nil, err
+	}
+
+	bx, by := cdhBase(otherX, otherY, curve, info.Cofactor)
+	x, y, err := ScalarMultBlind(bx, by, ownPriv, curve, rand, modes...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if isInfinity(x, y) {
+		return nil, nil, ErrIdentity
+	}
+	return x, y, nil
+}
+
+// AgreeCDH runs the ECC CDH primitive with typed keys and returns the shared
+// secret Z, which is the x-coordinate of P. See CDH for details.
+func AgreeCDH(own *KeyPair, other *PublicKey) (*SharedSecret, error) {
+	curve, err := checkCurves([]*KeyPair{own}, []*PublicKey{other})
+	if err != nil {
+		return nil, err
+	}
+	x, y, err := CDH(own.Private.D, other.X, other.Y, curve)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(x)
+	defer WipeInt(y)
+	return NewSharedSecret(x, curve)
+}
+
+// BlindAgreeCDH is similar to AgreeCDH, but uses the blinded primitive
+// BlindCDH.
+func BlindAgreeCDH(own *KeyPair, other *PublicKey, rand io.Reader, modes ...BlindMode) (*SharedSecret, error) {
+	curve, err := checkCurves([]*KeyPair{own}, []*PublicKey{other})
+	if err != nil {
+		return nil, err
+	}
+	x, y, err := BlindCDH(own.Private.D, other.X, other.Y, curve, rand, modes...)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(x)
+	defer WipeInt(y)
+	return NewSharedSecret(x, curve)
+}
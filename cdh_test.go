@@ -0,0 +1,121 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type CDHTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	alice, bob *KeyPair
+}
+
+func (s *CDHTestSuite) SetupTest() {
+	var err error
+	s.alice, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair for alice")
+	s.bob, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair for bob")
+}
+
+func (s *CDHTestSuite) TestReference() {
+	h, err := cofactor(s.Curve)
+	s.Require().NoError(err, "curve not registered")
+	k := new(big.Int).Mul(h, new(big.Int).SetBytes(s.alice.Private.D))
+	refX, refY := s.Curve.ScalarMult(s.bob.Public.X, s.bob.Public.Y, k.Bytes())
+
+	x, y, err := CDH(s.alice.Private.D, s.bob.Public.X, s.bob.Public.Y, s.Curve)
+	s.Require().NoError(err, "failed to run cdh")
+	s.Equal(refX.Text(16), x.Text(16), "cdh x differs")
+	s.Equal(refY.Text(16), y.Text(16), "cdh y differs")
+
+	for _, mode := range []BlindMode{0, BlindMultiplicative, BlindPoint} {
+		x, y, err = BlindCDH(s.alice.Private.D, s.bob.Public.X, s.bob.Public.Y, s.Curve, rand.Reader, mode)
+		s.Require().NoErrorf(err, "failed to run blind cdh with mode %d", mode)
+		s.Equalf(refX.Text(16), x.Text(16), "blind cdh x differs for mode %d", mode)
+		s.Equalf(refY.Text(16), y.Text(16), "blind cdh y differs for mode %d", mode)
+	}
+}
+
+func (s *CDHTestSuite) TestAgree() {
+	zA, err := AgreeCDH(s.alice, s.bob.Public)
+	s.Require().NoError(err, "failed to run agree for alice")
+	zB, err := BlindAgreeCDH(s.bob, s.alice.Public, rand.Reader)
+	s.Require().NoError(err, "failed to run blind agree for bob")
+	s.True(zA.Equal(zB), "shared secrets are not equal")
+	s.Len(zA.Bytes(), (s.Curve.Params().P.BitLen()+7)/8, "wrong length of shared secret")
+
+	other, err := GenerateKeyPair(elliptic.P224(), rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	if s.Curve != elliptic.P224() {
+		_, err = AgreeCDH(s.alice, other.Public)
+		s.Error(err, "keys of different curves accepted")
+	}
+}
+
+func TestCDHP224(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: elliptic.P224()})
+}
+
+func TestCDHP256(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: elliptic.P256()})
+}
+
+func TestCDHP384(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: elliptic.P384()})
+}
+
+func TestCDHP521(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: elliptic.P521()})
+}
+
+func TestCDHBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: BrainpoolP256r1()})
+}
+
+func TestCDHCofactor4(t *testing.T) {
+	suite.Run(t, &CDHTestSuite{Curve: testCurveH4()})
+}
+
+func TestCDHSmallSubgroup(t *testing.T) {
+	curve := testCurveH4()
+	kp, err := GenerateKeyPair(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+	other, err := GenerateKeyPair(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+
+	// the component of order 4 is removed by the cofactor multiplication
+	x, y, err := CDH(kp.Private.D, other.Public.X, other.Public.Y, curve)
+	if err != nil {
+		t.Fatalf("failed to run cdh: %v", err)
+	}
+	mx, my := curve.Add(other.Public.X, other.Public.Y, testCurveTx, testCurveTy)
+	for _, mode := range []BlindMode{0, BlindMultiplicative} {
+		bx, by, err := BlindCDH(kp.Private.D, mx, my, curve, rand.Reader, mode)
+		if err != nil {
+			t.Fatalf("failed to run blind cdh with mode %d: %v", mode, err)
+		}
+		if bx.Cmp(x) != 0 || by.Cmp(y) != 0 {
+			t.Errorf("component in small subgroup changed the shared secret with mode %d", mode)
+		}
+	}
+
+	// a point of small order results in the point at infinity
+	if _, _, err := CDH(kp.Private.D, testCurveTx, testCurveTy, curve); !errors.Is(err, ErrIdentity) {
+		t.Errorf("point of small order accepted: %v", err)
+	}
+}
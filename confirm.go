@@ -0,0 +1,173 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/hmac"
+	"fmt"
+	"hash"
+)
+
+// Role identifies the party of a key-agreement scheme. Party U is the
+// initiator and party V the responder of the key agreement.
+type Role int
+
+// Roles of the parties of a key-agreement scheme.
+const (
+	PartyU Role = iota
+	PartyV
+)
+
+// MAC calculates the MacTag for key confirmation.
+type MAC interface {
+	// Tag returns the MacTag of length bytes for data using key. An error
+	// is returned if the MAC cannot provide a tag of this length or its
+	// parameters are invalid.
+	Tag(key, data []byte, length int) ([]byte, error)
+}
+
+// HMAC implements MAC with HMAC and the given hash function. The result is
+// truncated to the requested length, which must not exceed the size of the
+// hash.
+type HMAC struct {
+	Hash func() hash.Hash
+}
+
+// Tag implements the MAC interface.
+func (m *HMAC) Tag(key, data []byte, length int) ([]byte, error) {
+	if m.Hash == nil {
+		return nil, fmt.Errorf("%w: missing hmac hash function", ErrInvalidParameters)
+	}
+	h := hmac.New(m.Hash, key)
+	if length <= 0 || length > h.Size() {
+		return nil, fmt.Errorf("%w: invalid hmac tag length %d", ErrInvalidParameters, length)
+	}
+	h.Write(data)
+	return h.Sum(nil)[:length], nil
+}
+
+// KMAC implements MAC with KMAC128 or KMAC256 of SP 800-185, depending on
+// the security strength, which must be either 128 or 256. The
+// customization string "KC" is used.
+type KMAC struct {
+	Security int
+}
+
+// Tag implements the MAC interface.
+func (m *KMAC) Tag(key, data []byte, length int) ([]byte, error) {
+	if m.Security != 128 && m.Security != 256 {
+		return nil, fmt.Errorf("%w: invalid kmac security strength %d", ErrInvalidParameters, m.Security)
+	}
+	if length <= 0 {
+		return nil, fmt.Errorf("%w: invalid kmac tag length %d", ErrInvalidParameters, length)
+	}
+	return kmac(m.Security, key, data, []byte("KC"), length), nil
+}
+
+// KeyConfirmation contains the parameters of key confirmation, as described
+// by section 5.9 of SP 800-56A Rev. 3. The MacKey is taken from the first
+// MacKeyLen bytes of the derived keying material.
+type KeyConfirmation struct {
+	// MAC is the algorithm used to calculate the MacTag.
+	MAC MAC
+
+	// MacKeyLen is the length of the MacKey in bytes.
+	MacKeyLen int
+
+	// TagLen is the length of the MacTag in bytes.
+	TagLen int
+
+	// Bilateral indicates that both parties provide a MacTag.
+	Bilateral bool
+}
+
+// MacTag calculates the MacTag of the provider P for the recipient R using
+// MacData = message_string || ID_P || ID_R || EphemData_P || EphemData_R ||
+// Text, where message_string is one of "KC_1_U", "KC_2_U", "KC_1_V" and
+// "KC_2_V" depending on the provider and on whether bilateral key
+// confirmation is used.
+func (kc *KeyConfirmation) MacTag(macKey []byte, provider Role, idP, idR, ephemDataP, ephemDataR, text []byte) ([]byte, error) {
+	if kc.MAC == nil {
+		return nil, fmt.Errorf("%w: missing key confirmation mac", ErrInvalidParameters)
+	}
+	msg := "KC_1_"
+	if kc.Bilateral {
+		msg = "KC_2_"
+	}
+	if provider == PartyU {
+		msg += "U"
+	} else {
+		msg += "V"
+	}
+
+	var data []byte
+	data = append(data, msg...)
+	data = append(data, idP...)
+	data = append(data, idR...)
+	data = append(data, ephemDataP...)
+	data = append(data, ephemDataR...)
+	data = append(data, text...)
+	return kc.MAC.Tag(macKey, data, kc.TagLen)
+}
+
+// check validates the parameters. The MAC validates its own parameters and
+// the tag length when it calculates a MacTag, so it is run once with a
+// dummy key.
+func (kc *KeyConfirmation) check() error {
+	if kc.MAC == nil {
+		return fmt.Errorf("%w: missing key confirmation mac", ErrInvalidParameters)
+	}
+	if kc.MacKeyLen <= 0 || kc.TagLen <= 0 {
+		return fmt.Errorf("%w: invalid key confirmation length", ErrInvalidParameters)
+	}
+	if _, err := kc.MAC.Tag(make([]byte, kc.MacKeyLen), nil, kc.TagLen); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Confirmation holds the MacKey and the public data that is needed to
+// provide and verify key confirmation for one party of a key agreement.
+type Confirmation struct {
+	config *KeyConfirmation
+	role   Role
+	macKey []byte
+	idU    []byte
+	idV    []byte
+	ephemU []byte
+	ephemV []byte
+}
+
+// Tag returns the MacTag of the own party. The optional text is appended to
+// the MacData.
+func (c *Confirmation) Tag(text []byte) ([]byte, error) {
+	if c.role == PartyU {
+		return c.config.MacTag(c.macKey, PartyU, c.idU, c.idV, c.ephemU, c.ephemV, text)
+	}
+	return c.config.MacTag(c.macKey, PartyV, c.idV, c.idU, c.ephemV, c.ephemU, text)
+}
+
+// Verify checks the MacTag received from the other party in constant time.
+// An error wrapping ErrAuthentication is returned if the tag is invalid.
+func (c *Confirmation) Verify(tag, text []byte) error {
+	var want []byte
+	var err error
+	if c.role == PartyU {
+		want, err = c.config.MacTag(c.macKey, PartyV, c.idV, c.idU, c.ephemV, c.ephemU, text)
+	} else {
+		want, err = c.config.MacTag(c.macKey, PartyU, c.idU, c.idV, c.ephemU, c.ephemV, text)
+	}
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(want, tag) {
+		return fmt.Errorf("%w: invalid key confirmation tag", ErrAuthentication)
+	}
+	return nil
+}
+
+// Destroy wipes the MacKey.
+func (c *Confirmation) Destroy() {
+	WipeBytes(c.macKey)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfirmTestSuite struct {
+	suite.Suite
+
+	curve      elliptic.Curve
+	config     SchemeConfig
+	idU        []byte
+	idV        []byte
+	uStatic    *KeyPair
+	uEphemeral *KeyPair
+	vStatic    *KeyPair
+	vEphemeral *KeyPair
+}
+
+func (s *ConfirmTestSuite) SetupTest() {
+	s.curve = elliptic.P256()
+	s.config = SchemeConfig{
+		KDF:    &OneStepKDF{Hash: sha256.New},
+		KeyLen: 32,
+		Confirmation: &KeyConfirmation{
+			MAC:       &HMAC{Hash: sha256.New},
+			MacKeyLen: 32,
+			TagLen:    16,
+			Bilateral: true,
+		},
+	}
+	s.idU = []byte("alice")
+	s.idV = []byte("bob")
+	s.uStatic = s.generateKeyPair()
+	s.uEphemeral = s.generateKeyPair()
+	s.vStatic = s.generateKeyPair()
+	s.vEphemeral = s.generateKeyPair()
+}
+
+// tag returns the MacTag of c and fails the test on errors.
+func (s *ConfirmTestSuite) tag(c *Confirmation, text []byte) []byte {
+	tag, err := c.Tag(text)
+	s.Require().NoError(err, "failed to calculate tag")
+	return tag
+}
+
+func (s *ConfirmTestSuite) generateKeyPair() *KeyPair {
+	kp, err := GenerateKeyPair(s.curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	return kp
+}
+
+func (s *ConfirmTestSuite) TestKMAC() {
+	key, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f")
+	data := []byte{0, 1, 2, 3}
+
+	s.Equal("e5780b0d3ea6f7d3a429c5706aa43a00fadbd7d49628839e3187243f456ee14e",
+		hex.EncodeToString(kmac(128, key, data, nil, 32)), "kmac128 sample 1")
+	s.Equal("3b1fba963cd8b0b59e8c1a6d71888b7143651af8ba0a7070c0979e2811324aa5",
+		hex.EncodeToString(kmac(128, key, data, []byte("My Tagged Application"), 32)), "kmac128 sample 2")
+	s.Equal("20c570c31346f703c9ac36c61c03cb64c3970d0cfc787e9b79599d273a68d2f7"+
+		"f69d4cc3de9d104a351689f27cf6f5951f0103f33f4f24871024d9c27773a8dd",
+		hex.EncodeToString(kmac(256, key, data, []byte("My Tagged Application"), 64)), "kmac256 sample 4")
+}
+
+func (s *ConfirmTestSuite) TestMacTag() {
+	kc := &KeyConfirmation{MAC: &HMAC{Hash: sha256.New}, MacKeyLen: 16, TagLen: 32}
+	macKey := []byte("0123456789abcdef")
+
+	h := hmac.New(sha256.New, macKey)
+	h.Write([]byte("KC_1_Vbobaliceeph-beph-atext"))
+	tag, err := kc.MacTag(macKey, PartyV, []byte("bob"), []byte("alice"), []byte("eph-b"), []byte("eph-a"), []byte("text"))
+	s.Require().NoError(err, "failed to calculate unilateral tag of v")
+	s.Equal(h.Sum(nil), tag, "unilateral tag of v")
+
+	kc.Bilateral = true
+	h.Reset()
+	h.Write([]byte("KC_2_Ualicebob"))
+	tag, err = kc.MacTag(macKey, PartyU, []byte("alice"), []byte("bob"), nil, nil, nil)
+	s.Require().NoError(err, "failed to calculate bilateral tag of u")
+	s.Equal(h.Sum(nil), tag, "bilateral tag of u")
+}
+
+func (s *ConfirmTestSuite) TestInvalidMAC() {
+	key, data := []byte("0123456789abcdef"), []byte("data")
+
+	_, err := (&HMAC{Hash: sha256.New}).Tag(key, data, 64)
+	s.True(errors.Is(err, ErrInvalidParameters), "tag longer than hmac accepted: %v", err)
+	_, err = (&HMAC{}).Tag(key, data, 16)
+	s.True(errors.Is(err, ErrInvalidParameters), "missing hash accepted: %v", err)
+
+	for _, security := range []int{0, 192} {
+		_, err = (&KMAC{Security: security}).Tag(key, data, 16)
+		s.Truef(errors.Is(err, ErrInvalidParameters), "kmac security strength %d accepted: %v", security, err)
+	}
+
+	kc := &KeyConfirmation{MAC: &HMAC{Hash: sha256.New}, MacKeyLen: 16, TagLen: 64}
+	_, err = kc.MacTag(key, PartyU, nil, nil, nil, nil, nil)
+	s.True(errors.Is(err, ErrInvalidParameters), "tag longer than hmac accepted: %v", err)
+}
+
+func (s *ConfirmTestSuite) TestFull() {
+	for _, mac := range []MAC{&HMAC{Hash: sha256.New}, &KMAC{Security: 128}, &KMAC{Security: 256}} {
+		s.config.Confirmation.MAC = mac
+		scheme := &FullMQV{s.config}
+
+		keyU, confU, err := scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+		s.Require().NoError(err, "failed to derive key for u")
+		keyV, confV, err := scheme.ConfirmKey(PartyV, s.idU, s.idV, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+		s.Require().NoError(err, "failed to derive key for v")
+		s.Equal(keyU, keyV, "keys are not equal")
+		s.Len(keyU, s.config.KeyLen, "invalid key length")
+
+		tagU := s.tag(confU, nil)
+		tagV := s.tag(confV, nil)
+		s.Len(tagU, s.config.Confirmation.TagLen, "invalid tag length")
+		s.NotEqual(tagU, tagV, "tags of u and v are equal")
+		s.NoError(confV.Verify(tagU, nil), "tag of u rejected")
+		s.NoError(confU.Verify(tagV, nil), "tag of v rejected")
+		s.Error(confU.Verify(tagU, nil), "own tag accepted")
+		s.Error(confV.Verify(tagU, []byte("text")), "tag with different text accepted")
+
+		tagU[0] ^= 1
+		err = confV.Verify(tagU, nil)
+		s.True(errors.Is(err, ErrAuthentication), "modified tag accepted: %v", err)
+
+		key, err := scheme.DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+		s.NoError(err, "failed to derive key without confirmation")
+		s.Equal(keyU, key, "confirmed key differs")
+	}
+}
+
+func (s *ConfirmTestSuite) TestWrongStaticKey() {
+	scheme := &FullMQV{s.config}
+	wrong := s.generateKeyPair()
+
+	_, confU, err := scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	_, confV, err := scheme.ConfirmKey(PartyV, s.idU, s.idV, wrong, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+
+	s.Error(confU.Verify(s.tag(confV, nil), nil), "tag with wrong static key accepted")
+}
+
+func (s *ConfirmTestSuite) TestOnePass() {
+	scheme := &OnePassMQV{s.config}
+	nonce := []byte("nonce of v")
+
+	keyU, confU, err := scheme.ConfirmKeyU(s.idU, s.idV, nonce, s.uStatic, s.uEphemeral, s.vStatic.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	keyV, confV, err := scheme.ConfirmKeyV(s.idU, s.idV, nonce, s.vStatic, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+
+	s.NoError(confV.Verify(s.tag(confU, nil), nil), "tag of u rejected")
+	s.NoError(confU.Verify(s.tag(confV, nil), nil), "tag of v rejected")
+}
+
+func (s *ConfirmTestSuite) TestInvalidConfig() {
+	s.config.Confirmation.TagLen = 33
+	scheme := &FullMQV{s.config}
+	_, _, err := scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Error(err, "tag longer than hmac accepted")
+
+	for _, security := range []int{0, 192} {
+		s.config.Confirmation.TagLen = 16
+		s.config.Confirmation.MAC = &KMAC{Security: security}
+		scheme = &FullMQV{s.config}
+		_, _, err = scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+		s.Truef(errors.Is(err, ErrInvalidParameters), "kmac security strength %d accepted: %v", security, err)
+	}
+
+	s.config.Confirmation = nil
+	scheme = &FullMQV{s.config}
+	_, _, err = scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Error(err, "missing confirmation accepted")
+}
+
+func TestConfirm(t *testing.T) {
+	suite.Run(t, new(ConfirmTestSuite))
+}
@@ -0,0 +1,550 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Types of the records.
+	recordData        = 1
+	recordCloseNotify = 2
+
+	// recordHeaderLen is the length of the record header, which consists
+	// of the type and the length of the encrypted payload.
+	recordHeaderLen = 3
+
+	// maxRecordPayload is the maximal length of the plaintext of a record.
+	maxRecordPayload = 16 << 10
+
+	// maxHandshakeMessage is the maximal length of a handshake message.
+	maxHandshakeMessage = 4 << 10
+
+	// connSecretSize is the length of the traffic secrets.
+	connSecretSize = 32
+
+	// defaultRekeyAfter is the default of ConnConfig.RekeyAfter.
+	defaultRekeyAfter = 1 << 30
+
+	// maxRecordsPerKey is the maximal number of records encrypted with the
+	// same traffic key.
+	maxRecordsPerKey = 1 << 24
+)
+
+var errConnClosed = errors.New("use of closed connection")
+
+// ConnConfig contains the parameters of a secure channel, see Client and
+// Server. Both parties must use the same SchemeConfig, Cipher and RekeyAfter.
+//
+// The handshake derives a traffic secret for each direction with the KDF of
+// the SchemeConfig. If KDF is nil, the one-step KDF with SHA-256 is used.
+// KeyLen and AlgorithmID are set by the channel and should be left empty.
+// Key confirmation is recommended, it is used as configured.
+type ConnConfig struct {
+	HandshakeConfig
+
+	// Cipher is the AEAD of the record layer. If Cipher is zero,
+	// AES256GCM is used.
+	Cipher Cipher
+
+	// RekeyAfter is the number of bytes after which the traffic key of a
+	// direction is replaced by a new key derived from the current one. If
+	// RekeyAfter is zero, the key is updated after 1 GiB.
+	RekeyAfter int64
+}
+
+// handshakeConfig returns the HandshakeConfig used by the channel.
+func (c *ConnConfig) handshakeConfig() (*HandshakeConfig, error) {
+	cph := c.cipher()
+	if _, err := cph.keySize(); err != nil {
+		return nil, err
+	}
+	config := c.HandshakeConfig
+	if config.KDF == nil {
+		config.KDF = &OneStepKDF{Hash: sha256.New}
+	}
+	config.KeyLen = 2 * connSecretSize
+	config.AlgorithmID = append([]byte("mqv-conn"), byte(cph))
+	return &config, nil
+}
+
+func (c *ConnConfig) cipher() Cipher {
+	if c.Cipher == 0 {
+		return AES256GCM
+	}
+	return c.Cipher
+}
+
+func (c *ConnConfig) rekeyAfter() int64 {
+	if c.RekeyAfter <= 0 {
+		return defaultRekeyAfter
+	}
+	return c.RekeyAfter
+}
+
+// halfConn contains the state of the record layer for one direction.
+type halfConn struct {
+	sync.Mutex
+
+	kdf        KDF
+	cipher     Cipher
+	rekeyAfter int64
+	secret     []byte
+	aead       cipher.AEAD
+	iv         [aeadNonceSize]byte
+	seq        uint64
+	bytes      int64
+	buf        []byte
+	err        error
+}
+
+// init sets the traffic secret of the first epoch.
+func (hc *halfConn) init(config *ConnConfig, kdf KDF, secret []byte) error {
+	hc.kdf = kdf
+	hc.cipher = config.cipher()
+	hc.rekeyAfter = config.rekeyAfter()
+	hc.buf = make([]byte, maxRecordPayload+aeadOverhead)
+	return hc.setSecret(secret)
+}
+
+// setSecret derives the traffic key and IV from the traffic secret.
+func (hc *halfConn) setSecret(secret []byte) error {
+	keySize, err := hc.cipher.keySize()
+	if err != nil {
+		return err
+	}
+	info := (&FixedInfo{AlgorithmID: []byte("mqv-conn key")}).Bytes()
+	km, err := hc.kdf.DeriveKey(secret, info, keySize+aeadNonceSize)
+	if err != nil {
+		return fmt.Errorf("failed to derive traffic key: %w", err)
+	}
+	defer WipeBytes(km)
+	aead, err := hc.cipher.aead(km[:keySize])
+	if err != nil {
+		return fmt.Errorf("failed to create cipher: %w", err)
+	}
+	WipeBytes(hc.secret)
+	hc.secret = secret
+	hc.aead = aead
+	copy(hc.iv[:], km[keySize:])
+	hc.seq = 0
+	hc.bytes = 0
+	return nil
+}
+
+// rekey replaces the traffic secret by a secret derived from it. The old
+// secret is wiped, so that the previous keys can not be recovered.
+func (hc *halfConn) rekey() error {
+	info := (&FixedInfo{AlgorithmID: []byte("mqv-conn rekey")}).Bytes()
+	next, err := hc.kdf.DeriveKey(hc.secret, info, len(hc.secret))
+	if err != nil {
+		return fmt.Errorf("failed to update traffic key: %w", err)
+	}
+	return hc.setSecret(next)
+}
+
+// nonce returns the nonce of the current record, which is the IV XORed with
+// the sequence number.
+func (hc *halfConn) nonce() []byte {
+	nonce := hc.iv
+	for i := 0; i < 8; i++ {
+		nonce[aeadNonceSize-1-i] ^= byte(hc.seq >> (8 * i))
+	}
+	return nonce[:]
+}
+
+// advance increments the sequence number after a record with n bytes of
+// payload and updates the traffic key if necessary.
+func (hc *halfConn) advance(n int) error {
+	hc.seq++
+	hc.bytes += int64(n)
+	if hc.bytes >= hc.rekeyAfter || hc.seq >= maxRecordsPerKey {
+		return hc.rekey()
+	}
+	return nil
+}
+
+// seal encrypts the payload and returns the record. The record header is
+// authenticated as additional data.
+func (hc *halfConn) seal(typ byte, payload []byte) ([]byte, error) {
+	record := make([]byte, recordHeaderLen, recordHeaderLen+len(payload)+aeadOverhead)
+	record[0] = typ
+	binary.BigEndian.PutUint16(record[1:], uint16(len(payload)+aeadOverhead))
+	record = hc.aead.Seal(record, hc.nonce(), payload, record[:recordHeaderLen])
+	if err := hc.advance(len(payload)); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// open decrypts the payload of a record in place.
+func (hc *halfConn) open(header, ct []byte) ([]byte, error) {
+	payload, err := hc.aead.Open(ct[:0], hc.nonce(), ct, header)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	if err := hc.advance(len(payload)); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
+
+// destroy wipes the traffic secret.
+func (hc *halfConn) destroy() {
+	WipeBytes(hc.secret)
+	hc.secret = nil
+}
+
+// Conn is a secure channel on top of a net.Conn, similar to the connections
+// of crypto/tls. Both parties are authenticated by their static keys with
+// the full MQV handshake, see Initiator and Responder. The data is sent in
+// records of at most 16 KiB, which are encrypted and authenticated with the
+// AEAD of the ConnConfig. The nonce of a record is derived from its sequence
+// number, so that replayed, reordered and dropped records are detected. The
+// end of the data is signaled with a close_notify record, a connection that
+// ends without one results in io.ErrUnexpectedEOF.
+//
+// The handshake is run by the first Read or Write, or explicitly with
+// Handshake. Errors of the underlying connection, including timeouts, are
+// permanent.
+type Conn struct {
+	conn     net.Conn
+	config   *ConnConfig
+	isClient bool
+
+	handshakeMu   sync.Mutex
+	handshakeDone int32
+	handshakeErr  error
+
+	in    halfConn
+	out   halfConn
+	input []byte
+}
+
+// Client returns a new secure channel using conn as the underlying
+// connection. The client is party U (the initiator) of the handshake.
+func Client(conn net.Conn, config *ConnConfig) *Conn {
+	return &Conn{conn: conn, config: config, isClient: true}
+}
+
+// Server returns a new secure channel using conn as the underlying
+// connection. The server is party V (the responder) of the handshake.
+func Server(conn net.Conn, config *ConnConfig) *Conn {
+	return &Conn{conn: conn, config: config}
+}
+
+// Handshake runs the handshake if it has not been run yet. Most callers do
+// not need to call it explicitly, because Read and Write run it
+// automatically.
+func (c *Conn) Handshake() error {
+	c.handshakeMu.Lock()
+	defer c.handshakeMu.Unlock()
+	if atomic.LoadInt32(&c.handshakeDone) == 1 || c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+	c.handshakeErr = c.handshake()
+	if c.handshakeErr == nil {
+		atomic.StoreInt32(&c.handshakeDone, 1)
+	}
+	return c.handshakeErr
+}
+
+// handshake runs the full MQV handshake and initializes the record layer.
+func (c *Conn) handshake() error {
+	if c.config == nil {
+		return errors.New("missing conn config")
+	}
+	config, err := c.config.handshakeConfig()
+	if err != nil {
+		return err
+	}
+
+	var key []byte
+	if c.isClient {
+		key, err = c.runInitiator(config)
+	} else {
+		key, err = c.runResponder(config)
+	}
+	if err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	defer WipeBytes(key)
+
+	// the first secret is used from the client to the server
+	outSecret := append([]byte(nil), key[:connSecretSize]...)
+	inSecret := append([]byte(nil), key[connSecretSize:]...)
+	if !c.isClient {
+		outSecret, inSecret = inSecret, outSecret
+	}
+	if err := c.out.init(c.config, config.KDF, outSecret); err != nil {
+		return err
+	}
+	return c.in.init(c.config, config.KDF, inSecret)
+}
+
+// runInitiator runs the handshake of the client and returns a copy of the
+// session key.
+func (c *Conn) runInitiator(config *HandshakeConfig) ([]byte, error) {
+	initiator, err := NewInitiator(config)
+	if err != nil {
+		return nil, err
+	}
+	defer initiator.Destroy()
+
+	msg, err := initiator.Start()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.writeHandshake(msg); err != nil {
+		return nil, err
+	}
+	if msg, err = c.readHandshake(); err != nil {
+		return nil, err
+	}
+	if msg, err = initiator.Finish(msg); err != nil {
+		return nil, err
+	}
+	if msg != nil {
+		if err := c.writeHandshake(msg); err != nil {
+			return nil, err
+		}
+	}
+	key, err := initiator.SessionKey()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), key...), nil
+}
+
+// runResponder runs the handshake of the server and returns a copy of the
+// session key.
+func (c *Conn) runResponder(config *HandshakeConfig) ([]byte, error) {
+	responder, err := NewResponder(config)
+	if err != nil {
+		return nil, err
+	}
+	defer responder.Destroy()
+
+	msg, err := c.readHandshake()
+	if err != nil {
+		return nil, err
+	}
+	if msg, err = responder.Respond(msg); err != nil {
+		return nil, err
+	}
+	if err := c.writeHandshake(msg); err != nil {
+		return nil, err
+	}
+	if config.Confirmation != nil && config.Confirmation.Bilateral {
+		if msg, err = c.readHandshake(); err != nil {
+			return nil, err
+		}
+		if err := responder.Finish(msg); err != nil {
+			return nil, err
+		}
+	}
+	key, err := responder.SessionKey()
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), key...), nil
+}
+
+// writeHandshake writes a handshake message with a 2-byte length prefix.
+func (c *Conn) writeHandshake(msg []byte) error {
+	buf := make([]byte, 2, 2+len(msg))
+	binary.BigEndian.PutUint16(buf, uint16(len(msg)))
+	_, err := c.conn.Write(append(buf, msg...))
+	return err
+}
+
+// readHandshake reads a handshake message written by writeHandshake.
+func (c *Conn) readHandshake() ([]byte, error) {
+	var length [2]byte
+	if _, err := io.ReadFull(c.conn, length[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	n := int(binary.BigEndian.Uint16(length[:]))
+	if n > maxHandshakeMessage {
+		return nil, fmt.Errorf("%w: handshake message too long", ErrInvalidMessage)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, msg); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return msg, nil
+}
+
+// unexpectedEOF converts io.EOF into io.ErrUnexpectedEOF, because the
+// underlying connection must not end before close_notify.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Read reads decrypted data from the connection. It returns io.EOF after
+// the other party has sent close_notify.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	c.in.Lock()
+	defer c.in.Unlock()
+	for len(c.input) == 0 {
+		if c.in.err != nil {
+			return 0, c.in.err
+		}
+		if err := c.readRecord(); err != nil {
+			c.in.err = err
+			c.in.destroy()
+		}
+	}
+	n := copy(b, c.input)
+	c.input = c.input[n:]
+	return n, nil
+}
+
+// readRecord reads and decrypts the next record.
+func (c *Conn) readRecord() error {
+	var header [recordHeaderLen]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err != nil {
+		return unexpectedEOF(err)
+	}
+	n := int(binary.BigEndian.Uint16(header[1:]))
+	if n < aeadOverhead || n > len(c.in.buf) {
+		return fmt.Errorf("%w: invalid record length", ErrInvalidMessage)
+	}
+	ct := c.in.buf[:n]
+	if _, err := io.ReadFull(c.conn, ct); err != nil {
+		return unexpectedEOF(err)
+	}
+	payload, err := c.in.open(header[:], ct)
+	if err != nil {
+		return err
+	}
+
+	switch header[0] {
+	case recordData:
+		c.input = payload
+		return nil
+	case recordCloseNotify:
+		if len(payload) != 0 {
+			return fmt.Errorf("%w: invalid close_notify", ErrInvalidMessage)
+		}
+		return io.EOF
+	}
+	return fmt.Errorf("%w: unknown record type %d", ErrInvalidMessage, header[0])
+}
+
+// Write encrypts and writes the data to the connection.
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	c.out.Lock()
+	defer c.out.Unlock()
+	n := 0
+	for len(b) > 0 {
+		m := len(b)
+		if m > maxRecordPayload {
+			m = maxRecordPayload
+		}
+		if err := c.writeRecord(recordData, b[:m]); err != nil {
+			return n, err
+		}
+		n += m
+		b = b[m:]
+	}
+	return n, c.out.err
+}
+
+// writeRecord encrypts and writes a record. Errors are permanent.
+func (c *Conn) writeRecord(typ byte, payload []byte) error {
+	if c.out.err != nil {
+		return c.out.err
+	}
+	record, err := c.out.seal(typ, payload)
+	if err == nil {
+		_, err = c.conn.Write(record)
+	}
+	if err != nil {
+		c.out.err = err
+		c.out.destroy()
+	}
+	return err
+}
+
+// CloseWrite sends close_notify, after which no more data can be written.
+// The underlying connection is not closed.
+func (c *Conn) CloseWrite() error {
+	if atomic.LoadInt32(&c.handshakeDone) != 1 {
+		return errors.New("handshake not completed")
+	}
+	c.out.Lock()
+	defer c.out.Unlock()
+	if c.out.err == errConnClosed {
+		return nil
+	}
+	if err := c.writeRecord(recordCloseNotify, nil); err != nil {
+		return err
+	}
+	c.out.err = errConnClosed
+	c.out.destroy()
+	return nil
+}
+
+// Close sends close_notify if the handshake has been completed and closes
+// the underlying connection.
+func (c *Conn) Close() error {
+	var notifyErr error
+	if atomic.LoadInt32(&c.handshakeDone) == 1 {
+		notifyErr = c.CloseWrite()
+	}
+	if err := c.conn.Close(); err != nil {
+		return err
+	}
+	return notifyErr
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// SetDeadline sets the read and write deadlines of the underlying
+// connection. Since errors are permanent, a connection can not be used after
+// a timeout.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the read deadline of the underlying connection.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the write deadline of the underlying connection.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
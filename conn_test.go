@@ -0,0 +1,213 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ConnTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	client, server *KeyPair
+}
+
+func (s *ConnTestSuite) SetupTest() {
+	var err error
+	s.client, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the client")
+	s.server, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the server")
+}
+
+func (s *ConnTestSuite) config(own *KeyPair, peer *PublicKey) *ConnConfig {
+	return &ConnConfig{
+		HandshakeConfig: HandshakeConfig{
+			SchemeConfig: SchemeConfig{
+				Confirmation: &KeyConfirmation{
+					MAC:       &HMAC{Hash: sha256.New},
+					MacKeyLen: 32,
+					TagLen:    16,
+					Bilateral: true,
+				},
+			},
+			StaticKey:     own,
+			PeerStaticKey: peer,
+		},
+	}
+}
+
+// pipe returns a connected client and server.
+func (s *ConnTestSuite) pipe(clientConfig, serverConfig *ConnConfig) (*Conn, *Conn) {
+	c, sc := net.Pipe()
+	return Client(c, clientConfig), Server(sc, serverConfig)
+}
+
+// runHandshake runs the handshake of both parties concurrently.
+func runHandshake(client, server *Conn) (error, error) {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- server.Handshake()
+	}()
+	err := client.Handshake()
+	if err != nil {
+		// unblock the server
+		client.conn.Close()
+	}
+	return err, <-errc
+}
+
+func (s *ConnTestSuite) TestReadWrite() {
+	for _, c := range []Cipher{AES256GCM, ChaCha20Poly1305} {
+		clientConfig := s.config(s.client, s.server.Public)
+		serverConfig := s.config(s.server, s.client.Public)
+		clientConfig.Cipher, serverConfig.Cipher = c, c
+		clientConfig.RekeyAfter, serverConfig.RekeyAfter = 1000, 1000
+		client, server := s.pipe(clientConfig, serverConfig)
+
+		data := make([]byte, 3*maxRecordPayload+123)
+		_, err := rand.Read(data)
+		s.Require().NoError(err, "failed to read random data")
+
+		errc := make(chan error, 1)
+		go func() {
+			// echo the data and close the connection
+			received, err := ioutil.ReadAll(server)
+			if err == nil {
+				_, err = server.Write(received)
+			}
+			if err == nil {
+				err = server.Close()
+			}
+			errc <- err
+		}()
+
+		n, err := client.Write(data)
+		s.Require().NoErrorf(err, "failed to write with cipher %d", c)
+		s.Equal(len(data), n, "short write")
+		s.Require().NoError(client.CloseWrite(), "failed to send close_notify")
+		_, err = client.Write(data)
+		s.Error(err, "write after close_notify accepted")
+
+		echo, err := ioutil.ReadAll(client)
+		s.Require().NoErrorf(err, "failed to read with cipher %d", c)
+		s.Require().NoError(<-errc, "server failed")
+		s.Equal(data, echo, "echo differs")
+		s.NoError(client.Close(), "failed to close")
+	}
+}
+
+func (s *ConnTestSuite) TestWithoutConfirmation() {
+	clientConfig := s.config(s.client, s.server.Public)
+	serverConfig := s.config(s.server, s.client.Public)
+	clientConfig.Confirmation, serverConfig.Confirmation = nil, nil
+	client, server := s.pipe(clientConfig, serverConfig)
+
+	go func() {
+		server.Write([]byte("hello"))
+	}()
+	buf := make([]byte, 5)
+	_, err := io.ReadFull(client, buf)
+	s.Require().NoError(err, "failed to read")
+	s.Equal("hello", string(buf), "data differs")
+}
+
+func (s *ConnTestSuite) TestWrongKey() {
+	other, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+
+	// the server expects another client
+	client, server := s.pipe(s.config(s.client, s.server.Public), s.config(s.server, other.Public))
+	clientErr, serverErr := runHandshake(client, server)
+	s.Error(clientErr, "handshake with wrong client key succeeded for the client")
+	s.Error(serverErr, "handshake with wrong client key succeeded for the server")
+
+	// without key confirmation, the first record is rejected
+	clientConfig := s.config(s.client, s.server.Public)
+	serverConfig := s.config(s.server, other.Public)
+	clientConfig.Confirmation, serverConfig.Confirmation = nil, nil
+	client, server = s.pipe(clientConfig, serverConfig)
+	go func() {
+		client.Write([]byte("hello"))
+	}()
+	_, err = server.Read(make([]byte, 5))
+	s.True(errors.Is(err, ErrAuthentication), "record with wrong key accepted: %v", err)
+}
+
+func (s *ConnTestSuite) TestTruncated() {
+	client, server := s.pipe(s.config(s.client, s.server.Public), s.config(s.server, s.client.Public))
+	clientErr, serverErr := runHandshake(client, server)
+	s.Require().NoError(clientErr, "handshake failed for the client")
+	s.Require().NoError(serverErr, "handshake failed for the server")
+
+	// the underlying connection is closed without close_notify
+	go func() {
+		client.Write([]byte("hello"))
+		client.conn.Close()
+	}()
+	data, err := ioutil.ReadAll(server)
+	s.Equal("hello", string(data), "data differs")
+	s.Equal(io.ErrUnexpectedEOF, err, "truncation not detected")
+}
+
+func (s *ConnTestSuite) TestRecords() {
+	clientConfig := s.config(s.client, s.server.Public)
+	var out, in halfConn
+	secret := make([]byte, connSecretSize)
+	s.Require().NoError(out.init(clientConfig, &OneStepKDF{Hash: sha256.New}, append([]byte(nil), secret...)), "failed to init")
+	s.Require().NoError(in.init(clientConfig, &OneStepKDF{Hash: sha256.New}, append([]byte(nil), secret...)), "failed to init")
+
+	record1, err := out.seal(recordData, []byte("first"))
+	s.Require().NoError(err, "failed to seal")
+	record2, err := out.seal(recordData, []byte("second"))
+	s.Require().NoError(err, "failed to seal")
+	s.Equal(uint64(2), out.seq, "wrong sequence number")
+
+	// reordered records are rejected
+	_, err = in.open(record2[:recordHeaderLen], append([]byte(nil), record2[recordHeaderLen:]...))
+	s.True(errors.Is(err, ErrAuthentication), "reordered record accepted: %v", err)
+
+	payload, err := in.open(record1[:recordHeaderLen], append([]byte(nil), record1[recordHeaderLen:]...))
+	s.Require().NoError(err, "failed to open")
+	s.Equal("first", string(payload), "payload differs")
+
+	// the header is authenticated
+	modified := append([]byte(nil), record2...)
+	modified[0] = recordCloseNotify
+	_, err = in.open(modified[:recordHeaderLen], modified[recordHeaderLen:])
+	s.True(errors.Is(err, ErrAuthentication), "modified header accepted: %v", err)
+
+	// rekeying replaces the secret
+	old := append([]byte(nil), out.secret...)
+	s.Require().NoError(out.rekey(), "failed to rekey")
+	s.False(bytes.Equal(old, out.secret), "secret not updated")
+	s.Equal(uint64(0), out.seq, "sequence number not reset")
+}
+
+func TestConnP224(t *testing.T) {
+	suite.Run(t, &ConnTestSuite{Curve: elliptic.P224()})
+}
+
+func TestConnP256(t *testing.T) {
+	suite.Run(t, &ConnTestSuite{Curve: elliptic.P256()})
+}
+
+func TestConnP384(t *testing.T) {
+	suite.Run(t, &ConnTestSuite{Curve: elliptic.P384()})
+}
+
+func TestConnP521(t *testing.T) {
+	suite.Run(t, &ConnTestSuite{Curve: elliptic.P521()})
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"sync"
+)
+
+// CurveInfo describes a curve of the registry. The values must not be
+// modified after the curve has been registered.
+type CurveInfo struct {
+	// Name is the name of the curve, e.g. "P-256" or "brainpoolP256r1".
+	Name string
+
+	// OID is the ASN.1 object identifier of the named curve. It may be nil
+	// for curves without an OID.
+	OID asn1.ObjectIdentifier
+
+	// Curve implements the arithmetic of the curve.
+	Curve elliptic.Curve
+
+	// Cofactor is the number of points on the curve divided by the order n
+	// of the base point.
+	Cofactor *big.Int
+}
+
+// FieldBytes returns the byte length of the field size p, which is the
+// length of encoded field elements like the shared secret.
+func (ci *CurveInfo) FieldBytes() int {
+	return (ci.Curve.Params().P.BitLen() + 7) >> 3
+}
+
+// OrderBytes returns the byte length of the order n, which is the length of
+// private keys.
+func (ci *CurveInfo) OrderBytes() int {
+	return (ci.Curve.Params().N.BitLen() + 7) >> 3
+}
+
+// AvfBits returns ceil(f/2) with f = ceil(log2(n)). The associative value
+// function keeps this number of bits of the x-coordinate and sets the next
+// higher bit, see section 5.7.2.2 of SP 800-56A Rev. 3.
+func (ci *CurveInfo) AvfBits() int {
+	return avfBits(ci.Curve.Params().N)
+}
+
+var (
+	curvesMu      sync.RWMutex
+	curves        []*CurveInfo
+	curvesByCurve = map[elliptic.Curve]*CurveInfo{}
+	curvesByName  = map[string]*CurveInfo{}
+	curvesByOID   = map[string]*CurveInfo{}
+)
+
+func init() {
+	for _, ci := range []*CurveInfo{
+		{Name: "P-224", OID: asn1.ObjectIdentifier{1, 3, 132, 0, 33}, Curve: elliptic.P224(), Cofactor: one},
+		{Name: "P-256", OID: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, Curve: elliptic.P256(), Cofactor: one},
+		{Name: "P-384", OID: asn1.ObjectIdentifier{1, 3, 132, 0, 34}, Curve: elliptic.P384(), Cofactor: one},
+		{Name: "P-521", OID: asn1.ObjectIdentifier{1, 3, 132, 0, 35}, Curve: elliptic.P521(), Cofactor: one},
+		{Name: "brainpoolP256r1", OID: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}, Curve: BrainpoolP256r1(), Cofactor: one},
+		{Name: "brainpoolP384r1", OID: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}, Curve: BrainpoolP384r1(), Cofactor: one},
+		{Name: "brainpoolP512r1", OID: asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}, Curve: BrainpoolP512r1(), Cofactor: one},
+		{Name: "secp256k1", OID: asn1.ObjectIdentifier{1, 3, 132, 0, 10}, Curve: Secp256k1(), Cofactor: one},
+	} {
+		if err := RegisterCurveInfo(ci); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterCurve registers an elliptic curve with its cofactor h (the number
+// of points on the curve divided by the order n of the base point) under the
+// name of its parameters. See RegisterCurveInfo for details.
+func RegisterCurve(curve elliptic.Curve, h *big.Int) error {
+	if curve == nil || curve.Params() == nil {
+		return errors.New("missing curve")
+	}
+	return RegisterCurveInfo(&CurveInfo{Name: curve.Params().Name, Curve: curve, Cofactor: h})
+}
+
+// RegisterCurveInfo adds a curve to the registry. This makes the curve
+// usable with MQV, BlindMQV and the schemes of this package. The shared
+// secret is multiplied by the cofactor, so points with a component in a
+// small subgroup do not affect the result. The elliptic.Curve value must be
+// comparable. The curves P-224, P-256, P-384, P-521, brainpoolP256r1,
+// brainpoolP384r1, brainpoolP512r1 and secp256k1 are registered by default.
+// An error is returned if the curve, its name or its OID is already
+// registered with different values.
+//
+// Curves defined by elliptic.CurveParams must have a = -3. The scalar
+// multiplications are calculated in constant time for them. Curves with other
+// coefficients can be defined with NewWeierstrassCurve.
+func RegisterCurveInfo(ci *CurveInfo) error {
+	if ci == nil || ci.Curve == nil || ci.Curve.Params() == nil {
+		return errors.New("missing curve")
+	}
+	if !reflect.TypeOf(ci.Curve).Comparable() {
+		return fmt.Errorf("curve %q is not comparable", ci.Name)
+	}
+	if ci.Cofactor == nil || ci.Cofactor.Sign() <= 0 {
+		return fmt.Errorf("invalid cofactor %v for curve %q", ci.Cofactor, ci.Name)
+	}
+
+	curvesMu.Lock()
+	defer curvesMu.Unlock()
+	if old, ok := curvesByCurve[ci.Curve]; ok {
+		if old.Name != ci.Name || !old.OID.Equal(ci.OID) || old.Cofactor.Cmp(ci.Cofactor) != 0 {
+			return fmt.Errorf("curve %q is already registered with name %q and cofactor %v", ci.Name, old.Name, old.Cofactor)
+		}
+		return nil
+	}
+	if _, ok := curvesByName[ci.Name]; ok && ci.Name != "" {
+		return fmt.Errorf("curve name %q is already registered", ci.Name)
+	}
+	if _, ok := curvesByOID[ci.OID.String()]; ok && len(ci.OID) > 0 {
+		return fmt.Errorf("curve oid %v is already registered", ci.OID)
+	}
+
+	info := &CurveInfo{
+		Name:     ci.Name,
+		OID:      append(asn1.ObjectIdentifier(nil), ci.OID...),
+		Curve:    ci.Curve,
+		Cofactor: new(big.Int).Set(ci.Cofactor),
+	}
+	curves = append(curves, info)
+	curvesByCurve[info.Curve] = info
+	if info.Name != "" {
+		curvesByName[info.Name] = info
+	}
+	if len(info.OID) > 0 {
+		curvesByOID[info.OID.String()] = info
+	}
+	return nil
+}
+
+// LookupCurve returns the registered curve for curve. Curves are found by
+// identity first. Otherwise the domain parameters are compared, which finds
+// wrapped curves and deserialized parameters as well. ErrUnsupportedCurve is
+// returned if the curve is not registered.
+func LookupCurve(curve elliptic.Curve) (*CurveInfo, error) {
+	if curve == nil {
+		return nil, fmt.Errorf("%w: missing curve", ErrUnsupportedCurve)
+	}
+	curvesMu.RLock()
+	defer curvesMu.RUnlock()
+	if reflect.TypeOf(curve).Comparable() {
+		if ci, ok := curvesByCurve[curve]; ok {
+			return ci, nil
+		}
+	}
+	params := curve.Params()
+	if params == nil {
+		return nil, fmt.Errorf("%w without parameters", ErrUnsupportedCurve)
+	}
+	for _, ci := range curves {
+		if equalParams(ci.Curve.Params(), params) {
+			return ci, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnsupportedCurve, params.Name)
+}
+
+// CurveByName returns the registered curve with the given name.
+func CurveByName(name string) (*CurveInfo, error) {
+	curvesMu.RLock()
+	defer curvesMu.RUnlock()
+	if ci, ok := curvesByName[name]; ok && name != "" {
+		return ci, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnsupportedCurve, name)
+}
+
+// CurveByOID returns the registered curve with the given ASN.1 object
+// identifier.
+func CurveByOID(oid asn1.ObjectIdentifier) (*CurveInfo, error) {
+	curvesMu.RLock()
+	defer curvesMu.RUnlock()
+	if ci, ok := curvesByOID[oid.String()]; ok && len(oid) > 0 {
+		return ci, nil
+	}
+	return nil, fmt.Errorf("%w %v", ErrUnsupportedCurve, oid)
+}
+
+// equalParams reports whether a and b describe the same curve. The name is
+// ignored. The coefficient a is determined by the other values, since the
+// base point is on the curve.
+func equalParams(a, b *elliptic.CurveParams) bool {
+	if b == nil || b.P == nil || b.N == nil || b.B == nil || b.Gx == nil || b.Gy == nil {
+		return false
+	}
+	return a.BitSize == b.BitSize && a.P.Cmp(b.P) == 0 && a.N.Cmp(b.N) == 0 &&
+		a.B.Cmp(b.B) == 0 && a.Gx.Cmp(b.Gx) == 0 && a.Gy.Cmp(b.Gy) == 0
+}
+
+// canonicalCurve returns the registered implementation of curve, which
+// differs from curve for wrapped curves and deserialized parameters. Curves
+// that are not registered are returned unchanged.
+func canonicalCurve(curve elliptic.Curve) elliptic.Curve {
+	if ci, err := LookupCurve(curve); err == nil {
+		return ci.Curve
+	}
+	return curve
+}
+
+// cofactor returns the cofactor (number of points on the elliptic curve vs.
+// number of elements in the cyclic group) of the elliptic curve. The curve
+// has to be registered.
+func cofactor(curve elliptic.Curve) (*big.Int, error) {
+	ci, err := LookupCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	return ci.Cofactor, nil
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	testCurveOnce sync.Once
+	testCurve     *elliptic.CurveParams
+
+	// point of order 4 on testCurve
+	testCurveTx, testCurveTy *big.Int
+)
+
+// testCurveH4 returns a small curve y^2 = x^3 - 3x + b over a 64 bit prime
+// field with cofactor 4, which is registered on first use.
+func testCurveH4() *elliptic.CurveParams {
+	testCurveOnce.Do(func() {
+		testCurve = &elliptic.CurveParams{
+			Name:    "test-h4",
+			P:       bigFromHex("d5d85e8d00460d6b"),
+			N:       bigFromHex("357617a348947809"),
+			B:       bigFromHex("1579da0a61b2480c"),
+			Gx:      bigFromHex("2bceb5ed1e377336"),
+			Gy:      bigFromHex("7732fc4608105e09"),
+			BitSize: 64,
+		}
+		testCurveTx = bigFromHex("7d8b46733107ce22")
+		testCurveTy = bigFromHex("24c95050439ce967")
+		if err := RegisterCurve(testCurve, big.NewInt(4)); err != nil {
+			panic(err)
+		}
+	})
+	return testCurve
+}
+
+type CurvesTestSuite struct {
+	suite.Suite
+
+	curve *elliptic.CurveParams
+}
+
+func (s *CurvesTestSuite) SetupTest() {
+	s.curve = testCurveH4()
+}
+
+func (s *CurvesTestSuite) generateKeyPair() *KeyPair {
+	kp, err := GenerateKeyPair(s.curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	return kp
+}
+
+func (s *CurvesTestSuite) TestCofactor() {
+	h, err := cofactor(s.curve)
+	s.Require().NoError(err, "registered curve not found")
+	s.Equal("4", h.String(), "wrong cofactor")
+
+	h, err = cofactor(elliptic.P256())
+	s.Require().NoError(err, "default curve not found")
+	s.Equal("1", h.String(), "wrong cofactor")
+
+	// copied parameters and wrapped curves are found by their parameters
+	params := *s.curve
+	h, err = cofactor(&params)
+	s.Require().NoError(err, "copied parameters not found")
+	s.Equal("4", h.String(), "wrong cofactor")
+
+	h, err = cofactor(struct{ elliptic.Curve }{s.curve})
+	s.Require().NoError(err, "wrapped curve not found")
+	s.Equal("4", h.String(), "wrong cofactor")
+
+	params.Gx = new(big.Int).Add(params.Gx, one)
+	_, err = cofactor(&params)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+}
+
+func (s *CurvesTestSuite) TestRegisterCurve() {
+	s.NoError(RegisterCurve(s.curve, big.NewInt(4)), "registering the same cofactor twice failed")
+	s.Error(RegisterCurve(s.curve, big.NewInt(2)), "different cofactor accepted")
+	s.Error(RegisterCurve(elliptic.P256(), big.NewInt(4)), "cofactor of P-256 changed")
+	s.Error(RegisterCurve(nil, one), "missing curve accepted")
+	s.Error(RegisterCurve(s.curve, nil), "missing cofactor accepted")
+	s.Error(RegisterCurve(s.curve, big.NewInt(0)), "zero cofactor accepted")
+
+	type sliceCurve struct {
+		elliptic.Curve
+		_ []byte
+	}
+	s.Error(RegisterCurve(sliceCurve{Curve: s.curve}, one), "non-comparable curve accepted")
+}
+
+func (s *CurvesTestSuite) TestLookup() {
+	for _, tc := range []struct {
+		name  string
+		oid   asn1.ObjectIdentifier
+		curve elliptic.Curve
+	}{
+		{"P-224", asn1.ObjectIdentifier{1, 3, 132, 0, 33}, elliptic.P224()},
+		{"P-256", asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, elliptic.P256()},
+		{"P-384", asn1.ObjectIdentifier{1, 3, 132, 0, 34}, elliptic.P384()},
+		{"P-521", asn1.ObjectIdentifier{1, 3, 132, 0, 35}, elliptic.P521()},
+		{"brainpoolP256r1", asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 7}, BrainpoolP256r1()},
+		{"brainpoolP384r1", asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 11}, BrainpoolP384r1()},
+		{"brainpoolP512r1", asn1.ObjectIdentifier{1, 3, 36, 3, 3, 2, 8, 1, 1, 13}, BrainpoolP512r1()},
+		{"secp256k1", asn1.ObjectIdentifier{1, 3, 132, 0, 10}, Secp256k1()},
+	} {
+		ci, err := CurveByName(tc.name)
+		s.Require().NoErrorf(err, "curve %s not found by name", tc.name)
+		s.Equalf(tc.curve, ci.Curve, "wrong curve for name %s", tc.name)
+		s.Truef(tc.oid.Equal(ci.OID), "wrong oid for %s", tc.name)
+		s.Equalf("1", ci.Cofactor.String(), "wrong cofactor for %s", tc.name)
+
+		byOID, err := CurveByOID(tc.oid)
+		s.Require().NoErrorf(err, "curve %s not found by oid", tc.name)
+		s.Equalf(ci, byOID, "wrong curve for oid %v", tc.oid)
+
+		byCurve, err := LookupCurve(tc.curve)
+		s.Require().NoErrorf(err, "curve %s not found", tc.name)
+		s.Equalf(ci, byCurve, "wrong curve for %s", tc.name)
+
+		params := tc.curve.Params()
+		s.Equalf((params.P.BitLen()+7)/8, ci.FieldBytes(), "wrong field length for %s", tc.name)
+		s.Equalf((params.N.BitLen()+7)/8, ci.OrderBytes(), "wrong order length for %s", tc.name)
+		s.Equalf((params.N.BitLen()+1)/2, ci.AvfBits(), "wrong avf length for %s", tc.name)
+	}
+
+	ci, err := CurveByName("test-h4")
+	s.Require().NoError(err, "registered curve not found by name")
+	s.Equal(elliptic.Curve(s.curve), ci.Curve, "wrong curve")
+	s.Nil(ci.OID, "unexpected oid")
+	s.Equal(31, ci.AvfBits(), "wrong avf length")
+
+	_, err = CurveByName("unknown")
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	_, err = CurveByName("")
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	_, err = CurveByOID(asn1.ObjectIdentifier{1, 2, 3})
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	_, err = CurveByOID(nil)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	_, err = LookupCurve(nil)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	_, err = UnmarshalPublicKey(nil, []byte{4})
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+}
+
+func (s *CurvesTestSuite) TestRegisterCurveInfo() {
+	s.Require().NoError(RegisterCurveInfo(&CurveInfo{
+		Name: "P-256", OID: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}, Curve: elliptic.P256(), Cofactor: one,
+	}), "registering the same curve twice failed")
+
+	params := *s.curve
+	params.Name = "test-h4-copy"
+	s.Error(RegisterCurveInfo(&CurveInfo{Name: "P-256", Curve: &params, Cofactor: big.NewInt(4)}),
+		"duplicate name accepted")
+	s.Error(RegisterCurveInfo(&CurveInfo{Name: params.Name, OID: asn1.ObjectIdentifier{1, 3, 132, 0, 10}, Curve: &params, Cofactor: big.NewInt(4)}),
+		"duplicate oid accepted")
+	s.Error(RegisterCurveInfo(&CurveInfo{Name: "P-256", OID: asn1.ObjectIdentifier{1, 2, 3}, Curve: elliptic.P256(), Cofactor: one}),
+		"different oid accepted")
+	s.Error(RegisterCurveInfo(nil), "missing curve info accepted")
+
+	_, err := CurveByName(params.Name)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "rejected curve was registered: %v", err)
+}
+
+func (s *CurvesTestSuite) TestAgree() {
+	alice, aliceEphemeral := s.generateKeyPair(), s.generateKeyPair()
+	bob, bobEphemeral := s.generateKeyPair(), s.generateKeyPair()
+
+	z1, err := Agree(alice, aliceEphemeral, bob.Public, bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run agree for alice")
+	z2, err := BlindAgree(bob, bobEphemeral, alice.Public, aliceEphemeral.Public, rand.Reader)
+	s.Require().NoError(err, "failed to run blinded agree for bob")
+	s.True(z1.Equal(z2), "shared secrets are not equal")
+}
+
+func (s *CurvesTestSuite) TestSmallSubgroup() {
+	t := &PublicKey{Curve: s.curve, X: testCurveTx, Y: testCurveTy}
+	tx, ty := s.curve.Double(t.X, t.Y)
+	t2 := &PublicKey{Curve: s.curve, X: tx, Y: ty}
+
+	err := ValidatePublicKeyPartial(t)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "point of order 4 accepted: %v", err)
+	err = ValidatePublicKeyPartial(t2)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "point of order 2 accepted: %v", err)
+
+	// Q + T has a component in the subgroup of order 4
+	kp := s.generateKeyPair()
+	mx, my := s.curve.Add(kp.Public.X, kp.Public.Y, t.X, t.Y)
+	mixed := &PublicKey{Curve: s.curve, X: mx, Y: my}
+	s.NoError(ValidatePublicKeyPartial(mixed), "partial validation rejected mixed point")
+	err = ValidatePublicKeyFull(mixed)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "full validation accepted mixed point: %v", err)
+
+	own := s.generateKeyPair()
+	scheme := &FullMQV{}
+	_, err = scheme.DeriveKey(nil, nil, own, own, mixed, kp.Public)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "mixed static key accepted: %v", err)
+	_, err = scheme.DeriveKey(nil, nil, own, own, kp.Public, t)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "ephemeral key of small order accepted: %v", err)
+}
+
+func (s *CurvesTestSuite) TestCofactorMultiplication() {
+	// The cofactor multiplication removes the component in the small
+	// subgroup of the (partially validated) ephemeral key.
+	own, ownEphemeral := s.generateKeyPair(), s.generateKeyPair()
+	other, otherEphemeral := s.generateKeyPair(), s.generateKeyPair()
+	ex, ey := s.curve.Add(otherEphemeral.Public.X, otherEphemeral.Public.Y, testCurveTx, testCurveTy)
+
+	ds, de, xe := own.Private.D, ownEphemeral.Private.D, ownEphemeral.Public.X
+	refX, refY, err := refMQV(ds, de, xe, other.Public.X, other.Public.Y, ex, ey, s.curve, big.NewInt(4))
+	s.Require().NoError(err, "failed to run reference mqv")
+
+	x, y, err := MQV(ds, de, xe, other.Public.X, other.Public.Y, ex, ey, s.curve)
+	s.Require().NoError(err, "failed to run mqv")
+	s.Equal(refX.Text(16), x.Text(16), "mqv x differs")
+	s.Equal(refY.Text(16), y.Text(16), "mqv y differs")
+
+	for _, mode := range []BlindMode{0, BlindMultiplicative, BlindPoint} {
+		x, y, err = BlindMQV(ds, de, xe, other.Public.X, other.Public.Y, ex, ey, s.curve, rand.Reader, mode)
+		s.Require().NoErrorf(err, "failed to run blind mqv with mode %d", mode)
+		s.Equalf(refX.Text(16), x.Text(16), "blind mqv x differs for mode %d", mode)
+		s.Equalf(refY.Text(16), y.Text(16), "blind mqv y differs for mode %d", mode)
+	}
+}
+
+func TestCurves(t *testing.T) {
+	suite.Run(t, new(CurvesTestSuite))
+}
+
+func TestReferenceCofactor4(t *testing.T) {
+	suite.Run(t, &ReferenceTestSuite{Curve: testCurveH4()})
+}
@@ -14,10 +14,86 @@
 // agreement. In this case, the sender uses the static key of the other party
 // twice (its safe to pass a key twice, once as static key and once as ephemeral
 // key), and the receiver uses his own static key twice to decode the message.
+// OnePassInitiate and OnePassRespond implement both sides with the correct
+// argument order.
 //
 // In addition to the basic MQV primitive, this package also implements a
 // blinded version BlindMQV, which blinds the keys before doing the computations
-// in order to prevent side channel attacks.
+// in order to prevent side channel attacks. Multiplicative
+// blinding of the scalar and randomized projective coordinates of the point can
+// be selected as additional countermeasures with BlindMode.
+//
+// The functions Agree and BlindAgree run the same primitives on typed
+// PublicKey and KeyPair values, which carry their curve and keep the own
+// keys apart from the other party's keys. They return the shared secret as
+// SharedSecret, which always has the fixed length of the field size.
+//
+// The schemes FullMQV and OnePassMQV build the complete key agreements
+// C(2e, 2s) and C(1e, 2s) on top of the primitive and derive keying material
+// from the shared secret with a key-derivation function.
+//
+// The key-derivation functions of SP 800-56C Rev. 2 are available as
+// OneStepKDF, OneStepHMACKDF and TwoStepKDF. FixedInfo encodes the context
+// data that is bound to the derived keying material.
+//
+// Key confirmation as described by section 5.9 of SP 800-56A can be added to
+// the schemes with KeyConfirmation, using either HMAC or KMAC to calculate the
+// MacTags.
+//
+// The curves P-224, P-256, P-384, P-521, brainpoolP256r1, brainpoolP384r1,
+// brainpoolP512r1 (RFC 5639) and secp256k1 are supported by default. The
+// brainpool curves and secp256k1 are provided as WeierstrassCurve, which
+// implements elliptic.Curve for arbitrary coefficients a in constant time.
+// Other curves, including curves with a cofactor h > 1, can be added with
+// RegisterCurve. The shared secret is multiplied by the cofactor and the
+// public-key validation rejects points in small subgroups.
+//
+// Registered curves can be looked up by name or ASN.1 object identifier with
+// CurveByName and CurveByOID. Public keys are encoded as uncompressed points
+// with MarshalPublicKey or as PKIX SubjectPublicKeyInfo with
+// MarshalPKIXPublicKey.
+//
+// FFCMQV and BlindFFCMQV implement the finite field variant of the MQV primitive
+// (MQV2 and MQV1) for the safe-prime groups of RFC 7919 (FFDHE2048 to
+// FFDHE8192) and RFC 3526 (MODP2048 to MODP8192) as well as for domain
+// parameters generated as described by FIPS 186-4. Domain parameters and public
+// keys are validated with ValidateFFCParams and ValidateFFCPublicKeyFull.
+//
+// HMQV and FHMQV implement the hashed variants of MQV, which bind the ephemeral
+// public keys to the identifiers of both parties. They use the same key types
+// as MQV, blinded versions are provided by BlindHMQV and BlindFHMQV.
+//
+// The ECC CDH primitive (CDH and BlindCDH) is used by the sibling schemes
+// FullUnified C(2e, 2s), EphemeralUnified C(2e, 0s), OnePassUnified C(1e, 2s)
+// and OnePassDH C(1e, 1s), which share the key types, the key derivation and
+// the key confirmation with the MQV schemes.
+//
+// Seal and Open implement hybrid public-key encryption on top of the one-pass
+// mode: the sender encrypts a message to the static public key of the recipient
+// and authenticates it with its own static key. The AEAD key and nonce for
+// AES-256-GCM or ChaCha20-Poly1305 are derived from the shared secret, the
+// versioned envelope carries the curve OID and the ephemeral public key.
+//
+// NewStreamWriter and NewStreamReader encrypt large payloads in chunks with the
+// STREAM construction, so that the plaintext never has to be held in memory.
+// The header carries the ephemeral public key and the KeyID of the sender, the
+// reader authenticates every chunk and can seek if the source implements
+// io.Seeker.
+//
+// SealMulti and OpenMulti encrypt a message once for several recipients. A
+// random content-encryption key is wrapped for every recipient with the one-pass
+// mode and each recipient finds its stanza by the KeyID of its static key.
+//
+// Initiator and Responder run the full MQV scheme as an interactive handshake.
+// They produce and consume the wire messages, enforce the order of the
+// messages, wipe the ephemeral keys after use and end with the session key and
+// optional key confirmation.
+//
+// Client and Server wrap a net.Conn in a secure channel similar to crypto/tls.
+// Both parties are authenticated by their static keys with the full MQV
+// handshake, the data is sent in AEAD records with sequence numbers, the traffic
+// keys are updated after a configurable number of bytes and the end of the data
+// is signaled with close_notify.
 //
 // Please see
 // https://csrc.nist.gov/publications/detail/sp/800-56a/rev-3/final
@@ -5,10 +5,9 @@ package mqv
 
 import (
 	"crypto/elliptic"
+	"fmt"
 	"io"
 	"math/big"
-
-	"github.com/pkg/errors"
 )
 
 var genMask = []byte{0xff, 0x1, 0x3, 0x7, 0xf, 0x1f, 0x3f, 0x7f}
@@ -16,10 +15,16 @@ var genMask = []byte{0xff, 0x1, 0x3, 0x7, 0xf, 0x1f, 0x3f, 0x7f}
 // GenerateKey returns a public / private key pair. The private key is
 // generated using the given reader, which must return random data.
 func GenerateKey(params *elliptic.CurveParams, rand io.Reader) ([]byte, error) {
-	numBits := params.N.BitLen()
+	return generateScalar(params.N, rand)
+}
+
+// generateScalar returns a random number in the range [1, n-1] as a
+// big-endian byte slice with the byte length of n.
+func generateScalar(n *big.Int, rand io.Reader) ([]byte, error) {
+	numBits := n.BitLen()
 	numBytes := (numBits + 7) >> 3
 	constN := make(SubtleInt, SubtleIntSize(numBits))
-	constN.SetBytes(params.N.Bytes())
+	constN.SetBytes(n.Bytes())
 
 	priv := make([]byte, numBytes)
 	tmp := make(SubtleInt, len(constN))
@@ -28,7 +33,7 @@ func GenerateKey(params *elliptic.CurveParams, rand io.Reader) ([]byte, error) {
 	for {
 		_, err := io.ReadFull(rand, priv)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to generate random data")
+			return nil, &RandomError{Err: err}
 		}
 
 		// We have to mask off any excess bits in the case that the size of the
@@ -39,7 +44,7 @@ func GenerateKey(params *elliptic.CurveParams, rand io.Reader) ([]byte, error) {
 		priv[1] ^= 0x42
 
 		tmp.SetBytes(priv)
-		if tmp.Less(constN) == 1 {
+		if tmp.Less(constN)&^isZero(tmp) == 1 {
 			return priv, nil
 		}
 	}
@@ -48,20 +53,22 @@ func GenerateKey(params *elliptic.CurveParams, rand io.Reader) ([]byte, error) {
 // BlindKey blinds the original private key (p) with a random blind key (b)
 // and returns (p+b, -b) mod n.
 func BlindKey(priv []byte, params *elliptic.CurveParams, rand io.Reader) ([]byte, []byte, error) {
-	numBytes := ((params.N.BitLen() + 7) >> 3)
+	return blindKey(priv, params.N, rand)
+}
+
+// blindKey implements BlindKey for the group order.
+func blindKey(priv []byte, order *big.Int, rand io.Reader) ([]byte, []byte, error) {
+	numBytes := ((order.BitLen() + 7) >> 3)
 	n := make(SubtleInt, SubtleIntSize(8*numBytes))
-	n.SetBytes(params.N.Bytes())
+	n.SetBytes(order.Bytes())
 
 	if len(priv) > numBytes {
-		return nil, nil, errors.New("invalid private key")
+		return nil, nil, ErrInvalidPrivateKey
 	}
 
-	privConst := make(SubtleInt, len(n))
-	privConst.SetBytes(priv)
-
-	blindBytes, err := GenerateKey(params, rand)
+	blindBytes, err := generateScalar(order, rand)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to generate blind key")
+		return nil, nil, fmt.Errorf("failed to generate blind key: %w", err)
 	}
 	defer WipeBytes(blindBytes)
 
@@ -69,9 +76,15 @@ func BlindKey(priv []byte, params *elliptic.CurveParams, rand io.Reader) ([]byte
 	defer blind.SetZero()
 	blind.SetBytes(blindBytes)
 
+	// SetBytes aligns the bytes to the most significant end, so the private
+	// key has to be padded to the same length as n.
+	privPadded := make([]byte, numBytes)
+	defer WipeBytes(privPadded)
+	copy(privPadded[numBytes-len(priv):], priv)
+
 	privNew := make(SubtleInt, len(n))
 	defer privNew.SetZero()
-	privNew.SetBytes(priv)
+	privNew.SetBytes(privPadded)
 	privNew.AddMod(privNew, blind, n)
 
 	blind.Sub(n, blind)
@@ -79,20 +92,123 @@ func BlindKey(priv []byte, params *elliptic.CurveParams, rand io.Reader) ([]byte
 	return privNew.Bytes()[:numBytes], blind.Bytes()[:numBytes], nil
 }
 
+// BlindMode selects additional countermeasures for the blinded scalar
+// multiplications of ScalarMultBlind and BlindMQV. Modes can be combined with
+// a bitwise or. By default, the scalar is split into two random additive
+// shares (see BlindKey) and no further countermeasures are applied.
+type BlindMode uint
+
+const (
+	// BlindMultiplicative blinds the scalar k multiplicatively instead of
+	// additively. The point is multiplied by k * r^-1 mod n first and the
+	// result by r afterwards, where r is a random number in [1, n-1]. The
+	// point must be in the subgroup of order n, otherwise the cofactor has to
+	// be applied as well (like BlindMQV does).
+	BlindMultiplicative BlindMode = 1 << iota
+
+	// BlindPoint randomizes the projective representation (X, Y, Z) of the
+	// point before each scalar multiplication by a random Z coordinate, as
+	// proposed by Coron. This protects against differential power analysis
+	// that targets the representation of the point. The scalar
+	// multiplications use the constant time Montgomery ladder of this
+	// package, so the curve must be a WeierstrassCurve, one of the standard
+	// library curves or defined by elliptic.CurveParams.
+	BlindPoint
+)
+
+// blindMode combines all given modes.
+func blindMode(modes []BlindMode) BlindMode {
+	var mode BlindMode
+	for _, m := range modes {
+		mode |= m
+	}
+	return mode
+}
+
 // ScalarMultBlind is similar to to the elliptic.ScalarMult function, but it
 // does two scalar multiplications with the blinded keys instead and adds the
-// afterwards.
-func ScalarMultBlind(x *big.Int, y *big.Int, priv []byte, curve elliptic.Curve, rand io.Reader) (*big.Int, *big.Int, error) {
+// afterwards. Additional countermeasures can be selected by modes.
+// ErrInvalidPublicKey is returned if a coordinate of the point is not in the
+// range [0, p-1].
+func ScalarMultBlind(x *big.Int, y *big.Int, priv []byte, curve elliptic.Curve, rand io.Reader, modes ...BlindMode) (*big.Int, *big.Int, error) {
+	mode := blindMode(modes)
+	if err := checkRange(curve, x, y); err != nil {
+		return nil, nil, err
+	}
+	if mode&BlindMultiplicative != 0 {
+		return scalarMultBlindMul(curve, x, y, priv, one, rand, mode)
+	}
+
 	privBlind, privBlindInv, err := BlindKey(priv, curve.Params(), rand)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to blind key")
+		return nil, nil, fmt.Errorf("failed to blind key: %w", err)
+	}
+	defer WipeBytes(privBlind)
+	defer WipeBytes(privBlindInv)
+
+	x1, y1, err := scalarMultMode(curve, x, y, privBlind, rand, mode)
+	if err != nil {
+		return nil, nil, err
 	}
-	x1, y1 := curve.ScalarMult(x, y, privBlind)
-	x2, y2 := curve.ScalarMult(x, y, privBlindInv)
-	x3, y3 := curve.Add(x1, y1, x2, y2)
+	defer WipeInt(x1)
+	defer WipeInt(y1)
+
+	x2, y2, err := scalarMultMode(curve, x, y, privBlindInv, rand, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeInt(x2)
+	defer WipeInt(y2)
+
+	x3, y3 := addPoints(curve, x1, y1, x2, y2)
 	return x3, y3, nil
 }
 
+// scalarMultBlindMul calculates (h * k) * (x, y) with multiplicative
+// blinding of k, i.e. (h * r) * ((k * r^-1 mod n) * (x, y)) with a random r.
+// The result is correct for all points if k is reduced modulo n and h is
+// the cofactor, since h * (x, y) is in the subgroup of order n.
+func scalarMultBlindMul(curve elliptic.Curve, x, y *big.Int, k []byte, h *big.Int, rand io.Reader, mode BlindMode) (*big.Int, *big.Int, error) {
+	params := curve.Params()
+	n := subtleFromBytes(params.N.Bytes(), SubtleIntSize(params.N.BitLen()))
+
+	kInt := subtleModN(k, n)
+	defer kInt.SetZero()
+
+	rBytes, err := generateScalar(params.N, rand)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate blind factor: %w", err)
+	}
+	defer WipeBytes(rBytes)
+	r := subtleFromBytes(rBytes, len(n))
+	defer r.SetZero()
+
+	rInv := make(SubtleInt, len(n))
+	defer rInv.SetZero()
+	if rInv.ModInverse(r, n) != 1 {
+		return nil, nil, fmt.Errorf("%w %q: order is not prime", ErrUnsupportedCurve, params.Name)
+	}
+	kInt.MulMod(kInt, rInv, n)
+	kBytes := kInt.Bytes()
+	defer WipeBytes(kBytes)
+
+	x1, y1, err := scalarMultMode(curve, x, y, kBytes, rand, mode)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeInt(x1)
+	defer WipeInt(y1)
+
+	hInt := subtleFromBytes(h.Bytes(), SubtleIntSize(h.BitLen()))
+	rh := make(SubtleInt, len(r)+len(hInt))
+	defer rh.SetZero()
+	rh.Mul(r, hInt)
+	rhBytes := rh.Bytes()
+	defer WipeBytes(rhBytes)
+
+	return scalarMultMode(curve, x1, y1, rhBytes, rand, mode)
+}
+
 // WipeInt overrides the internal array of a big.Int with zeros.
 func WipeInt(x *big.Int) {
 	words := x.Bits()
@@ -0,0 +1,148 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// oidPublicKeyEC is the algorithm identifier id-ecPublicKey of RFC 5480.
+var oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// MarshalPublicKey encodes the public key as an uncompressed point
+// 0x04 || X || Y, as described by section 2.3.3 of SEC 1. Both coordinates
+// have the field byte length of the registered curve.
+func MarshalPublicKey(pub *PublicKey) ([]byte, error) {
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return nil, fmt.Errorf("%w: missing key", ErrInvalidPublicKey)
+	}
+	ci, err := LookupCurve(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	byteLen := ci.FieldBytes()
+	if pub.X.Sign() < 0 || pub.Y.Sign() < 0 || pub.X.BitLen() > 8*byteLen || pub.Y.BitLen() > 8*byteLen {
+		return nil, fmt.Errorf("%w: coordinates out of range", ErrInvalidPublicKey)
+	}
+
+	r := make([]byte, 1+2*byteLen)
+	r[0] = 4
+	x, y := pub.X.Bytes(), pub.Y.Bytes()
+	copy(r[1+byteLen-len(x):], x)
+	copy(r[1+2*byteLen-len(y):], y)
+	return r, nil
+}
+
+// KeyIDSize is the length of the key identifiers returned by KeyID.
+const KeyIDSize = 16
+
+// KeyID returns a short identifier of the public key, which is used by the
+// encryption formats to find the key of a party. It consists of the leftmost
+// KeyIDSize bytes of the SHA-256 hash of the uncompressed point.
+func KeyID(pub *PublicKey) ([]byte, error) {
+	point, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(point)
+	return sum[:KeyIDSize], nil
+}
+
+// UnmarshalPublicKey decodes an uncompressed point for the registered curve,
+// see MarshalPublicKey. The public key is validated partially (see
+// ValidatePublicKeyPartial) and uses the registered implementation of the
+// curve.
+func UnmarshalPublicKey(curve elliptic.Curve, data []byte) (*PublicKey, error) {
+	ci, err := LookupCurve(curve)
+	if err != nil {
+		return nil, err
+	}
+	byteLen := ci.FieldBytes()
+	if len(data) != 1+2*byteLen {
+		return nil, fmt.Errorf("%w: invalid length %d", ErrInvalidPublicKey, len(data))
+	}
+	if data[0] != 4 {
+		return nil, fmt.Errorf("%w: point is not uncompressed", ErrInvalidPublicKey)
+	}
+	pub := &PublicKey{
+		Curve: ci.Curve,
+		X:     new(big.Int).SetBytes(data[1 : 1+byteLen]),
+		Y:     new(big.Int).SetBytes(data[1+byteLen:]),
+	}
+	if err := ValidatePublicKeyPartial(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+// pkixPublicKey is the SubjectPublicKeyInfo structure of RFC 5280.
+type pkixPublicKey struct {
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// MarshalPKIXPublicKey encodes the public key as DER encoded
+// SubjectPublicKeyInfo with the named curve of the registry, as described by
+// RFC 5480. The curve must have an OID.
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	point, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	ci, err := LookupCurve(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(ci.OID) == 0 {
+		return nil, fmt.Errorf("%w %q: missing oid", ErrUnsupportedCurve, ci.Name)
+	}
+	params, err := asn1.Marshal(ci.OID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal curve oid: %w", err)
+	}
+
+	return asn1.Marshal(pkixPublicKey{
+		Algorithm: pkix.AlgorithmIdentifier{
+			Algorithm:  oidPublicKeyEC,
+			Parameters: asn1.RawValue{FullBytes: params},
+		},
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+// ParsePKIXPublicKey decodes a DER encoded SubjectPublicKeyInfo with a named
+// curve of the registry, see MarshalPKIXPublicKey.
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var pki pkixPublicKey
+	rest, err := asn1.Unmarshal(der, &pki)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after public key")
+	}
+	if !pki.Algorithm.Algorithm.Equal(oidPublicKeyEC) {
+		return nil, fmt.Errorf("unknown public key algorithm %v", pki.Algorithm.Algorithm)
+	}
+
+	var oid asn1.ObjectIdentifier
+	rest, err = asn1.Unmarshal(pki.Algorithm.Parameters.FullBytes, &oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse named curve: %w", err)
+	}
+	if len(rest) > 0 {
+		return nil, errors.New("trailing data after named curve")
+	}
+	ci, err := CurveByOID(oid)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalPublicKey(ci.Curve, pki.PublicKey.RightAlign())
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EncodingTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	kp *KeyPair
+}
+
+func (s *EncodingTestSuite) SetupTest() {
+	kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	s.kp = kp
+}
+
+func (s *EncodingTestSuite) TestPublicKey() {
+	ci, err := LookupCurve(s.Curve)
+	s.Require().NoError(err, "curve not registered")
+
+	data, err := MarshalPublicKey(s.kp.Public)
+	s.Require().NoError(err, "failed to marshal public key")
+	s.Len(data, 1+2*ci.FieldBytes(), "wrong length")
+	s.Equal(byte(4), data[0], "wrong point format")
+
+	pub, err := UnmarshalPublicKey(s.Curve, data)
+	s.Require().NoError(err, "failed to unmarshal public key")
+	s.Equal(s.Curve, pub.Curve, "wrong curve")
+	s.Equal(s.kp.Public.X.Text(16), pub.X.Text(16), "x differs")
+	s.Equal(s.kp.Public.Y.Text(16), pub.Y.Text(16), "y differs")
+
+	// leading zero bytes are kept
+	small := &PublicKey{Curve: s.Curve, X: big.NewInt(1), Y: big.NewInt(2)}
+	data, err = MarshalPublicKey(small)
+	s.Require().NoError(err, "failed to marshal small coordinates")
+	s.Len(data, 1+2*ci.FieldBytes(), "wrong length for small coordinates")
+}
+
+func (s *EncodingTestSuite) TestUnmarshalInvalid() {
+	data, err := MarshalPublicKey(s.kp.Public)
+	s.Require().NoError(err, "failed to marshal public key")
+
+	for name, invalid := range map[string][]byte{
+		"empty":      nil,
+		"truncated":  data[:len(data)-1],
+		"extended":   append(append([]byte(nil), data...), 0),
+		"compressed": append([]byte{2}, data[1:]...),
+		"infinity":   append([]byte{4}, make([]byte, len(data)-1)...),
+	} {
+		_, err := UnmarshalPublicKey(s.Curve, invalid)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "%s point accepted: %v", name, err)
+	}
+
+	offCurve := append([]byte(nil), data...)
+	offCurve[len(offCurve)-1] ^= 1
+	_, err = UnmarshalPublicKey(s.Curve, offCurve)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "point not on curve accepted: %v", err)
+}
+
+func (s *EncodingTestSuite) TestPKIX() {
+	der, err := MarshalPKIXPublicKey(s.kp.Public)
+	s.Require().NoError(err, "failed to marshal public key")
+
+	pub, err := ParsePKIXPublicKey(der)
+	s.Require().NoError(err, "failed to parse public key")
+	s.Equal(s.Curve, pub.Curve, "wrong curve")
+	s.Equal(s.kp.Public.X.Text(16), pub.X.Text(16), "x differs")
+	s.Equal(s.kp.Public.Y.Text(16), pub.Y.Text(16), "y differs")
+
+	_, err = ParsePKIXPublicKey(append(der, 0))
+	s.Error(err, "trailing data accepted")
+	_, err = ParsePKIXPublicKey(der[:len(der)-1])
+	s.Error(err, "truncated data accepted")
+}
+
+func (s *EncodingTestSuite) TestPKIXStdlib() {
+	if _, ok := s.Curve.(*WeierstrassCurve); ok {
+		s.T().Skip("curve is not supported by crypto/x509")
+	}
+	ecdsaPub := &ecdsa.PublicKey{Curve: s.Curve, X: s.kp.Public.X, Y: s.kp.Public.Y}
+	ref, err := x509.MarshalPKIXPublicKey(ecdsaPub)
+	s.Require().NoError(err, "failed to marshal public key with crypto/x509")
+
+	der, err := MarshalPKIXPublicKey(s.kp.Public)
+	s.Require().NoError(err, "failed to marshal public key")
+	s.Equal(ref, der, "encoding differs from crypto/x509")
+
+	pub, err := ParsePKIXPublicKey(ref)
+	s.Require().NoError(err, "failed to parse public key of crypto/x509")
+	s.Equal(s.kp.Public.X.Text(16), pub.X.Text(16), "x differs")
+
+	parsed, err := x509.ParsePKIXPublicKey(der)
+	s.Require().NoError(err, "crypto/x509 failed to parse public key")
+	s.Require().IsType(&ecdsa.PublicKey{}, parsed, "wrong key type")
+	s.Equal(s.kp.Public.Y.Text(16), parsed.(*ecdsa.PublicKey).Y.Text(16), "y differs")
+}
+
+func (s *EncodingTestSuite) TestKeyID() {
+	id, err := KeyID(s.kp.Public)
+	s.Require().NoError(err, "failed to calculate key id")
+	s.Len(id, KeyIDSize, "wrong length of key id")
+	id2, err := KeyID(&PublicKey{Curve: s.Curve, X: new(big.Int).Set(s.kp.Public.X), Y: new(big.Int).Set(s.kp.Public.Y)})
+	s.Require().NoError(err, "failed to calculate key id of copy")
+	s.Equal(id, id2, "key id of copy differs")
+
+	other, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	id2, err = KeyID(other.Public)
+	s.Require().NoError(err, "failed to calculate key id")
+	s.NotEqual(id, id2, "key ids of different keys are equal")
+
+	_, err = KeyID(nil)
+	s.Error(err, "missing key accepted")
+}
+
+func TestEncodingP224(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: elliptic.P224()})
+}
+
+func TestEncodingP256(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: elliptic.P256()})
+}
+
+func TestEncodingP384(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: elliptic.P384()})
+}
+
+func TestEncodingP521(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: elliptic.P521()})
+}
+
+func TestEncodingBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: BrainpoolP256r1()})
+}
+
+func TestEncodingSecp256k1(t *testing.T) {
+	suite.Run(t, &EncodingTestSuite{Curve: Secp256k1()})
+}
+
+func TestPKIXUnknownCurve(t *testing.T) {
+	kp, err := GenerateKeyPair(testCurveH4(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+	if _, err := MarshalPKIXPublicKey(kp.Public); !errors.Is(err, ErrUnsupportedCurve) {
+		t.Errorf("curve without oid accepted: %v", err)
+	}
+}
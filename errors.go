@@ -0,0 +1,67 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"errors"
+)
+
+// Sentinel errors returned by this package. They are usually wrapped with
+// additional context and should be checked with errors.Is. The messages
+// never contain secret values.
+var (
+	// ErrUnsupportedCurve is returned if the parameters of a curve, e.g.
+	// its cofactor, are unknown.
+	ErrUnsupportedCurve = errors.New("unsupported curve")
+
+	// ErrInvalidPublicKey is returned if a public key fails validation.
+	ErrInvalidPublicKey = errors.New("invalid public key")
+
+	// ErrInvalidPrivateKey is returned if a private key is out of range.
+	ErrInvalidPrivateKey = errors.New("invalid private key")
+
+	// ErrInvalidParameters is returned if FFC domain parameters or the
+	// parameters of a scheme, e.g. of key confirmation, fail validation.
+	ErrInvalidParameters = errors.New("invalid parameters")
+
+	// ErrIdentity is returned by the MQV primitives if the shared secret is
+	// the identity element, i.e. the point at infinity for ECC and 1 for FFC.
+	ErrIdentity = errors.New("shared secret is the identity element")
+
+	// ErrInvalidMessage is returned if an encrypted message is malformed or
+	// uses an unsupported version or algorithm.
+	ErrInvalidMessage = errors.New("invalid message")
+
+	// ErrAuthentication is returned if an encrypted message or a key
+	// confirmation tag fails authentication, e.g. because it was modified or
+	// the wrong keys were used.
+	ErrAuthentication = errors.New("message authentication failed")
+
+	// ErrUnknownKey is returned if the key ID of an encrypted message does
+	// not match any of the given keys.
+	ErrUnknownKey = errors.New("unknown key")
+
+	// ErrRandom is returned if the random number generator fails.
+	ErrRandom = errors.New("failed to read random data")
+)
+
+// RandomError is returned if the random number generator fails. It matches
+// ErrRandom with errors.Is and unwraps to the error of the reader.
+type RandomError struct {
+	Err error
+}
+
+func (e *RandomError) Error() string {
+	return ErrRandom.Error() + ": " + e.Err.Error()
+}
+
+// Is reports whether target is ErrRandom.
+func (e *RandomError) Is(target error) bool {
+	return target == ErrRandom
+}
+
+// Unwrap returns the error of the random number generator.
+func (e *RandomError) Unwrap() error {
+	return e.Err
+}
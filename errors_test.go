@@ -0,0 +1,133 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+type ErrorsTestSuite struct {
+	suite.Suite
+
+	curve elliptic.Curve
+	key   *KeyPair
+}
+
+func (s *ErrorsTestSuite) SetupTest() {
+	var err error
+	s.curve = elliptic.P256()
+	s.key, err = GenerateKeyPair(s.curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+}
+
+func (s *ErrorsTestSuite) TestUnsupportedCurve() {
+	params := *elliptic.P256().Params()
+	params.Name = "custom"
+	params.B = new(big.Int).Add(params.B, one)
+	pub := s.key.Public
+
+	_, _, err := MQV(s.key.Private.D, s.key.Private.D, pub.X, pub.X, pub.Y, pub.X, pub.Y, &params)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+	s.Contains(err.Error(), "custom", "curve name missing")
+
+	// point blinding requires the coefficients of the curve
+	wrapped := struct{ elliptic.Curve }{s.curve}
+	_, _, err = ScalarMultBlind(pub.X, pub.Y, s.key.Private.D, wrapped, rand.Reader, BlindPoint)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "unexpected error %v", err)
+}
+
+func (s *ErrorsTestSuite) TestInvalidPublicKey() {
+	invalid := &PublicKey{Curve: s.curve, X: s.key.Public.X, Y: new(big.Int).Add(s.key.Public.Y, one)}
+
+	err := ValidatePublicKeyPartial(invalid)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "unexpected error %v", err)
+
+	scheme := &FullMQV{}
+	_, err = scheme.DeriveKey(nil, nil, s.key, s.key, invalid, s.key.Public)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "unexpected error %v", err)
+
+	_, err = Agree(s.key, s.key, nil, s.key.Public)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "unexpected error %v", err)
+}
+
+func (s *ErrorsTestSuite) TestOutOfRange() {
+	big300 := new(big.Int).Lsh(one, 300)
+	for _, curve := range []elliptic.Curve{s.curve, BrainpoolP256r1(), Secp256k1()} {
+		kp, err := GenerateKeyPair(curve, rand.Reader)
+		s.Require().NoError(err, "failed to create key pair")
+		d, pub := kp.Private.D, kp.Public
+		name := curve.Params().Name
+
+		_, _, err = MQV(d, d, pub.X, big300, pub.Y, pub.X, pub.Y, curve)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "static key accepted by mqv on %s: %v", name, err)
+		_, _, err = BlindMQV(d, d, pub.X, pub.X, pub.Y, pub.X, big300, curve, rand.Reader)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "ephemeral key accepted by blinded mqv on %s: %v", name, err)
+		_, _, err = CDH(d, big300, big300, curve)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "key accepted by cdh on %s: %v", name, err)
+		_, _, err = BlindCDH(d, big300, pub.Y, curve, rand.Reader)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "key accepted by blinded cdh on %s: %v", name, err)
+
+		invalid := &PublicKey{Curve: curve, X: big300, Y: pub.Y}
+		_, err = HMQV(sha256.New, PartyU, nil, nil, kp, kp, invalid, pub)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "key accepted by hmqv on %s: %v", name, err)
+	}
+}
+
+func (s *ErrorsTestSuite) TestInvalidPrivateKey() {
+	d := s.curve.Params().N.Bytes()
+	_, err := NewPrivateKey(s.curve, d)
+	s.True(errors.Is(err, ErrInvalidPrivateKey), "unexpected error %v", err)
+	s.NotContains(err.Error(), hex.EncodeToString(d), "private key leaked")
+
+	_, _, err = BlindKey(make([]byte, 33), s.curve.Params(), rand.Reader)
+	s.True(errors.Is(err, ErrInvalidPrivateKey), "unexpected error %v", err)
+}
+
+func (s *ErrorsTestSuite) TestIdentity() {
+	pub := s.key.Public
+	k := avf(pub.X, avfBits(s.curve.Params().N))
+	k.ModInverse(k, s.curve.Params().N)
+	k.Sub(s.curve.Params().N, k)
+	x, y := s.curve.ScalarMult(pub.X, pub.Y, k.Bytes())
+
+	_, _, err := MQV(s.key.Private.D, s.key.Private.D, pub.X, x, y, pub.X, pub.Y, s.curve)
+	s.True(errors.Is(err, ErrIdentity), "unexpected error %v", err)
+
+	_, _, err = BlindMQV(s.key.Private.D, s.key.Private.D, pub.X, x, y, pub.X, pub.Y, s.curve, rand.Reader)
+	s.True(errors.Is(err, ErrIdentity), "unexpected error %v", err)
+}
+
+func (s *ErrorsTestSuite) TestRandom() {
+	_, err := GenerateKeyPair(s.curve, failingReader{})
+	s.True(errors.Is(err, ErrRandom), "unexpected error %v", err)
+	s.True(errors.Is(err, io.ErrUnexpectedEOF), "reader error not wrapped")
+
+	var randErr *RandomError
+	s.True(errors.As(err, &randErr), "unexpected error type %T", err)
+
+	pub := s.key.Public
+	_, _, err = BlindMQV(s.key.Private.D, s.key.Private.D, pub.X, pub.X, pub.Y, pub.X, pub.Y, s.curve, failingReader{})
+	s.True(errors.Is(err, ErrRandom), "unexpected error %v", err)
+	s.False(strings.Contains(err.Error(), hex.EncodeToString(s.key.Private.D)), "private key leaked")
+}
+
+func TestErrors(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
@@ -0,0 +1,344 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Minimal sizes of FFC domain parameters, see table 25 of SP 800-56A Rev. 3
+// (parameter sets FB and FC).
+const (
+	minFFCPBits = 2048
+	minFFCQBits = 224
+)
+
+// ffcPrimeRounds is the number of Miller-Rabin rounds used to test the
+// primality of p and q. ProbablyPrime applies a Baillie-PSW test as well.
+const ffcPrimeRounds = 20
+
+// FFCParams are the domain parameters of a finite field cryptography (FFC)
+// group: the prime modulus P, the prime order Q of the subgroup and its
+// generator G. The named safe-prime groups (e.g. FFDHE2048 or MODP2048) use
+// q = (p-1)/2 and g = 2, domain parameters generated as described by
+// FIPS 186-4 use a smaller q. The values must not be modified.
+type FFCParams struct {
+	Name    string
+	P, Q, G *big.Int
+}
+
+// FFCPublicKey represents a public key y = g^x mod p of a FFC group.
+type FFCPublicKey struct {
+	Params *FFCParams
+	Y      *big.Int
+}
+
+// FFCPrivateKey represents a private key of a FFC group. X is the private
+// exponent encoded as a big-endian byte slice.
+type FFCPrivateKey struct {
+	Params *FFCParams
+	X      []byte
+}
+
+// FFCKeyPair bundles a FFC private key with its corresponding public key.
+type FFCKeyPair struct {
+	Private *FFCPrivateKey
+	Public  *FFCPublicKey
+}
+
+// NewFFCPrivateKey returns a private key for the given exponent. The
+// exponent must be in the range [1, q-1].
+func NewFFCPrivateKey(params *FFCParams, x []byte) (*FFCPrivateKey, error) {
+	if len(x) > (params.Q.BitLen()+7)>>3 {
+		return nil, ErrInvalidPrivateKey
+	}
+	v := new(big.Int).SetBytes(x)
+	defer WipeInt(v)
+	if v.Sign() == 0 || v.Cmp(params.Q) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return &FFCPrivateKey{Params: params, X: x}, nil
+}
+
+// Public calculates the public key that belongs to the private key. The
+// exponentiation is calculated in constant time.
+func (priv *FFCPrivateKey) Public() *FFCPublicKey {
+	return &FFCPublicKey{Params: priv.Params, Y: ffcExp(priv.Params.G, priv.X, priv.Params.P)}
+}
+
+// NewFFCKeyPair returns the key pair of the given private key.
+func NewFFCKeyPair(priv *FFCPrivateKey) *FFCKeyPair {
+	return &FFCKeyPair{Private: priv, Public: priv.Public()}
+}
+
+// GenerateFFCKeyPair returns a new random key pair of the given group. The
+// private key is generated using the given reader, which must return random
+// data.
+func GenerateFFCKeyPair(params *FFCParams, rand io.Reader) (*FFCKeyPair, error) {
+	x, err := generateScalar(params.Q, rand)
+	if err != nil {
+		return nil, err
+	}
+	return NewFFCKeyPair(&FFCPrivateKey{Params: params, X: x}), nil
+}
+
+// ValidateFFCParams verifies that the domain parameters are valid, see
+// section 5.5.2 of SP 800-56A Rev. 3. The named safe-prime groups are
+// accepted without further checks. For other domain parameters, p must have
+// at least 2048 bits and q at least 224 bits, both must be prime, q must
+// divide p-1 and g must generate the subgroup of order q. Errors wrap
+// ErrInvalidParameters.
+func ValidateFFCParams(params *FFCParams) error {
+	if params == nil || params.P == nil || params.Q == nil || params.G == nil {
+		return fmt.Errorf("%w: missing parameters", ErrInvalidParameters)
+	}
+	for _, group := range safePrimeGroups() {
+		if params == group || equalFFCParams(params, group) {
+			return nil
+		}
+	}
+
+	p, q, g := params.P, params.Q, params.G
+	if p.BitLen() < minFFCPBits || q.BitLen() < minFFCQBits {
+		return fmt.Errorf("%w: p or q too small", ErrInvalidParameters)
+	}
+	if !p.ProbablyPrime(ffcPrimeRounds) {
+		return fmt.Errorf("%w: p is not prime", ErrInvalidParameters)
+	}
+	if !q.ProbablyPrime(ffcPrimeRounds) {
+		return fmt.Errorf("%w: q is not prime", ErrInvalidParameters)
+	}
+	pm1 := new(big.Int).Sub(p, one)
+	if new(big.Int).Mod(pm1, q).Sign() != 0 {
+		return fmt.Errorf("%w: q does not divide p-1", ErrInvalidParameters)
+	}
+	if g.Cmp(one) <= 0 || g.Cmp(pm1) >= 0 {
+		return fmt.Errorf("%w: generator out of range", ErrInvalidParameters)
+	}
+	if new(big.Int).Exp(g, q, p).Cmp(one) != 0 {
+		return fmt.Errorf("%w: generator is not in the subgroup of order q", ErrInvalidParameters)
+	}
+	return nil
+}
+
+// equalFFCParams reports whether a and b describe the same group. The name
+// is ignored.
+func equalFFCParams(a, b *FFCParams) bool {
+	return a.P.Cmp(b.P) == 0 && a.Q.Cmp(b.Q) == 0 && a.G.Cmp(b.G) == 0
+}
+
+// ValidateFFCPublicKeyFull implements the FFC full public-key validation
+// routine of section 5.6.2.3.1 of SP 800-56A Rev. 3. In addition to the
+// checks of ValidateFFCPublicKeyPartial, it verifies that y^q mod p = 1,
+// i.e. that y is in the subgroup of order q. The domain parameters are not
+// validated, see ValidateFFCParams. Static and ephemeral public keys should
+// be validated with this routine.
+func ValidateFFCPublicKeyFull(pub *FFCPublicKey) error {
+	if err := ValidateFFCPublicKeyPartial(pub); err != nil {
+		return err
+	}
+	if new(big.Int).Exp(pub.Y, pub.Params.Q, pub.Params.P).Cmp(one) != 0 {
+		return fmt.Errorf("%w: element is not in the subgroup of order q", ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// ValidateFFCPublicKeyPartial implements the FFC partial public-key
+// validation routine of section 5.6.2.3.2 of SP 800-56A Rev. 3. It verifies
+// that y is in the range [2, p-2].
+func ValidateFFCPublicKeyPartial(pub *FFCPublicKey) error {
+	if pub == nil || pub.Params == nil || pub.Params.P == nil || pub.Params.Q == nil || pub.Y == nil {
+		return fmt.Errorf("%w: missing key", ErrInvalidPublicKey)
+	}
+	pm1 := new(big.Int).Sub(pub.Params.P, one)
+	if pub.Y.Cmp(one) <= 0 || pub.Y.Cmp(pm1) >= 0 {
+		return fmt.Errorf("%w: element out of range", ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// ffcExp calculates x^e mod p in constant time with respect to the exponent
+// e, which is a big-endian byte slice. p must be odd and x < p.
+func ffcExp(x *big.Int, e []byte, p *big.Int) *big.Int {
+	size := SubtleIntSize(p.BitLen())
+	m := NewMontContext(subtleFromBytes(p.Bytes(), size))
+
+	xInt := subtleFromBytes(x.Bytes(), size)
+	defer xInt.SetZero()
+	eInt := subtleFromBytes(e, SubtleIntSize(8*len(e)))
+	defer eInt.SetZero()
+
+	z := make(SubtleInt, size)
+	defer z.SetZero()
+	m.Exp(z, xInt, eInt)
+	return z.Big()
+}
+
+// ffcMQVBase calculates otherEphemeralPublic * otherStaticPublic^avf(otherEphemeralPublic) mod p.
+func ffcMQVBase(otherStaticY, otherEphemeralY *big.Int, params *FFCParams) *big.Int {
+	avfOther := avf(otherEphemeralY, avfBits(params.Q))
+	defer WipeInt(avfOther)
+
+	b := new(big.Int).Exp(otherStaticY, avfOther, params.P)
+	b.Mul(b, otherEphemeralY)
+	return b.Mod(b, params.P)
+}
+
+// checkFFCParams returns an error if params can't be used by the FFC
+// primitives.
+func checkFFCParams(params *FFCParams) error {
+	if params == nil || params.P == nil || params.Q == nil || params.G == nil {
+		return fmt.Errorf("%w: missing parameters", ErrInvalidParameters)
+	}
+	if params.P.Cmp(one) <= 0 || params.P.Bit(0) == 0 || params.Q.Sign() <= 0 {
+		return fmt.Errorf("%w: invalid modulus", ErrInvalidParameters)
+	}
+	return nil
+}
+
+// FFCMQV implements the FFC MQV primitive (MQV2) that calculates a shared
+// secret based on the domain parameters, the own public and private keys and
+// the other party's public keys, see section 5.7.2.1 of SP 800-56A Rev. 3.
+// Z is calculated as (ye * ys^avf(ye))^S mod p, where ys and ye are the
+// other party's static and ephemeral public keys and S is the implicit
+// signature of the own keys. In the one-pass form (MQV1), the other party
+// only has a static key which is used twice with this primitive.
+// The exponentiation with S is calculated in constant time. The public keys
+// of the other party are not validated by this primitive, see
+// ValidateFFCPublicKeyFull. ErrIdentity is returned if Z is 1.
+func FFCMQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralY, otherStaticY, otherEphemeralY *big.Int, params *FFCParams) (*big.Int, error) {
+	if err := checkFFCParams(params); err != nil {
+		return nil, err
+	}
+
+	s := mqvSig(ownStaticPriv, ownEphemeralPriv, ownEphemeralY, avfBits(params.Q), params.Q, one)
+	defer WipeBytes(s)
+
+	b := ffcMQVBase(otherStaticY, otherEphemeralY, params)
+	defer WipeInt(b)
+
+	z := ffcExp(b, s, params.P)
+	if z.Cmp(one) == 0 {
+		return nil, ErrIdentity
+	}
+	return z, nil
+}
+
+// BlindFFCMQV implements the FFC MQV primitive with additional blinding
+// to prevent side channel attacks, analogous to BlindMQV.
+//
+// Both private keys are blinded by random numbers 0 < r < q, i.e. Z is
+// calculated as B^S1 * B^S2 mod p with B = ye * ys^avf(ye),
+// S1 = mqvSig(ownStaticPriv + r1, ownEphemeralPriv + r2) and
+// S2 = mqvSig(-r1, -r2). This requires that the public keys of the other
+// party are in the subgroup of order q, which is verified by
+// ValidateFFCPublicKeyFull. Like FFCMQV, it returns ErrIdentity if Z is 1.
+func BlindFFCMQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralY, otherStaticY, otherEphemeralY *big.Int, params *FFCParams, rand io.Reader) (*big.Int, error) {
+	if err := checkFFCParams(params); err != nil {
+		return nil, err
+	}
+
+	ownStaticPrivNew, ownStaticPrivRev, err := blindKey(ownStaticPriv, params.Q, rand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to blind static key: %w", err)
+	}
+	defer WipeBytes(ownStaticPrivNew)
+	defer WipeBytes(ownStaticPrivRev)
+
+	ownEphemeralPrivNew, ownEphemeralPrivRev, err := blindKey(ownEphemeralPriv, params.Q, rand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to blind ephemeral key: %w", err)
+	}
+	defer WipeBytes(ownEphemeralPrivNew)
+	defer WipeBytes(ownEphemeralPrivRev)
+
+	b := ffcMQVBase(otherStaticY, otherEphemeralY, params)
+	defer WipeInt(b)
+
+	s1 := mqvSig(ownStaticPrivNew, ownEphemeralPrivNew, ownEphemeralY, avfBits(params.Q), params.Q, one)
+	defer WipeBytes(s1)
+	z1 := ffcExp(b, s1, params.P)
+	defer WipeInt(z1)
+
+	s2 := mqvSig(ownStaticPrivRev, ownEphemeralPrivRev, ownEphemeralY, avfBits(params.Q), params.Q, one)
+	defer WipeBytes(s2)
+	z2 := ffcExp(b, s2, params.P)
+	defer WipeInt(z2)
+
+	z := new(big.Int).Mul(z1, z2)
+	z.Mod(z, params.P)
+	if z.Cmp(one) == 0 {
+		return nil, ErrIdentity
+	}
+	return z, nil
+}
+
+// checkFFCGroups returns an error unless all keys use the same domain
+// parameters.
+func checkFFCGroups(own []*FFCKeyPair, other []*FFCPublicKey) (*FFCParams, error) {
+	var params *FFCParams
+	check := func(p *FFCParams) error {
+		if params == nil {
+			params = p
+		} else if p != params {
+			return errors.New("keys use different domain parameters")
+		}
+		return nil
+	}
+	for _, kp := range own {
+		if kp == nil || kp.Private == nil || kp.Public == nil {
+			return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+		}
+		if err := check(kp.Private.Params); err != nil {
+			return nil, err
+		}
+		if err := check(kp.Public.Params); err != nil {
+			return nil, err
+		}
+	}
+	for _, pub := range other {
+		if pub == nil {
+			return nil, fmt.Errorf("%w: missing key", ErrInvalidPublicKey)
+		}
+		if err := check(pub.Params); err != nil {
+			return nil, err
+		}
+	}
+	return params, nil
+}
+
+// FFCAgree runs the FFC MQV primitive with typed keys and returns the shared
+// secret Z. It is the FFC counterpart of Agree, see FFCMQV for details.
+func FFCAgree(ownStatic, ownEphemeral *FFCKeyPair, otherStatic, otherEphemeral *FFCPublicKey) (*SharedSecret, error) {
+	params, err := checkFFCGroups([]*FFCKeyPair{ownStatic, ownEphemeral}, []*FFCPublicKey{otherStatic, otherEphemeral})
+	if err != nil {
+		return nil, err
+	}
+	z, err := FFCMQV(ownStatic.Private.X, ownEphemeral.Private.X, ownEphemeral.Public.Y,
+		otherStatic.Y, otherEphemeral.Y, params)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(z)
+	return NewFFCSharedSecret(z, params)
+}
+
+// BlindFFCAgree is similar to FFCAgree, but uses the blinded primitive
+// BlindFFCMQV.
+func BlindFFCAgree(ownStatic, ownEphemeral *FFCKeyPair, otherStatic, otherEphemeral *FFCPublicKey, rand io.Reader) (*SharedSecret, error) {
+	params, err := checkFFCGroups([]*FFCKeyPair{ownStatic, ownEphemeral}, []*FFCPublicKey{otherStatic, otherEphemeral})
+	if err != nil {
+		return nil, err
+	}
+	z, err := BlindFFCMQV(ownStatic.Private.X, ownEphemeral.Private.X, ownEphemeral.Public.Y,
+		otherStatic.Y, otherEphemeral.Y, params, rand)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(z)
+	return NewFFCSharedSecret(z, params)
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	testFFCOnce   sync.Once
+	testFFCParams *FFCParams
+)
+
+// testFFC2048 returns FFC domain parameters with a 2048 bit p and a 224 bit
+// q, which were generated like the parameters of FIPS 186-4.
+func testFFC2048() *FFCParams {
+	testFFCOnce.Do(func() {
+		testFFCParams = &FFCParams{
+			Name: "test-2048-224",
+			P: bigFromHex("" +
+				"bf1b474995ad0e4fe6482f7281a97a27601b9ee442969af78343a8abf0fe0350" +
+				"402807e98484779ee79b0eee4e64d5f994d02ef2c3a8593a0b54e1cf0b5b35be" +
+				"046b42b1fdc3cccb87ac8faa46e365accde03755e1a8b37b08de388d541adbb2" +
+				"f0995a71702a5d0a917a224649c17248e76804cf3f87004d1f7b2d47857e4faa" +
+				"aba2420f98bb1388ede2b9df20150ef5e9d0370cf31db3b0176662999271f7d1" +
+				"e77e9d181776049cde113669323418386013f259ba80e0ff195a2cbefb2d709f" +
+				"5d3c56f6cb62a6a27dd9105a8aefc921a909853540683f853fb7f0f67dce8c74" +
+				"5c9b809a4f130a3214d4cc5eacbedb986cf41b3add694a59125f672cd7420181"),
+			Q: bigFromHex("f8904ef702eaa24448a71fb63d24293af2c47dc3e6c952c6dfaa8ee7"),
+			G: bigFromHex("" +
+				"af2531b6100fcb6b58eacff44759cab6fc3b7b29df2b2677dec73216a4cdb128" +
+				"794df77bbdb0ddf640766052b76a836fdd13e0900036782ec3e7c74963091207" +
+				"83a5123c8bd483381b775064e2e9f84ac8484f4cb0664d1f383bd8829be0bfba" +
+				"b8dcdec7564b6db84d4ac960846ec329230403a2343b2b95484f975f08c9ebd5" +
+				"5dc592147b5f73946243088a4e5318ac9e40d0430f9fdc255ad3b574ef4502ea" +
+				"e5dc83d5a7692cd9aa8e8908206cf8df8cec4d3ad11ab5c926a03632dc195ddf" +
+				"7d0b3eb662617675427ec1d3d37345b5f17de4a661e5b990c9f4bbb3eddc8fd2" +
+				"d4c327a5e58c03e0a977115d23809eef466b578958a41830fbb6d7cd385b8875"),
+		}
+	})
+	return testFFCParams
+}
+
+// refFFCMQV is a straightforward implementation of the FFC MQV primitive of
+// section 5.7.2.1 of SP 800-56A Rev. 3.
+func refFFCMQV(xs, xe, ye, otherYs, otherYe *big.Int, params *FFCParams) *big.Int {
+	p, q := params.P, params.Q
+	w := uint((q.BitLen() + 1) / 2)
+	pow := new(big.Int).Lsh(one, w)
+	mask := new(big.Int).Sub(pow, one)
+
+	// S = (xe + T * xs) mod q with T = (ye mod 2^w) + 2^w
+	t := new(big.Int).And(ye, mask)
+	t.Add(t, pow)
+	s := new(big.Int).Mul(t, xs)
+	s.Add(s, xe)
+	s.Mod(s, q)
+
+	// Z = (otherYe * otherYs^T) ^ S with T = (otherYe mod 2^w) + 2^w
+	t.And(otherYe, mask)
+	t.Add(t, pow)
+	z := new(big.Int).Exp(otherYs, t, p)
+	z.Mul(z, otherYe)
+	z.Mod(z, p)
+	return z.Exp(z, s, p)
+}
+
+type FFCTestSuite struct {
+	Params *FFCParams
+	suite.Suite
+
+	aliceStatic    *FFCKeyPair
+	aliceEphemeral *FFCKeyPair
+	bobStatic      *FFCKeyPair
+	bobEphemeral   *FFCKeyPair
+}
+
+func (s *FFCTestSuite) SetupTest() {
+	s.aliceStatic = s.generateKeyPair("alice static")
+	s.aliceEphemeral = s.generateKeyPair("alice ephemeral")
+	s.bobStatic = s.generateKeyPair("bob static")
+	s.bobEphemeral = s.generateKeyPair("bob ephemeral")
+}
+
+func (s *FFCTestSuite) generateKeyPair(name string) *FFCKeyPair {
+	kp, err := GenerateFFCKeyPair(s.Params, rand.Reader)
+	s.Require().NoErrorf(err, "failed to create key pair %q", name)
+	return kp
+}
+
+func (s *FFCTestSuite) TestParams() {
+	s.NoError(ValidateFFCParams(s.Params), "valid domain parameters rejected")
+
+	params := *s.Params
+	params.Name = "copy"
+	s.NoError(ValidateFFCParams(&params), "copied domain parameters rejected")
+}
+
+func (s *FFCTestSuite) TestPublic() {
+	x := new(big.Int).SetBytes(s.aliceStatic.Private.X)
+	y := new(big.Int).Exp(s.Params.G, x, s.Params.P)
+	s.Equal(y.Text(16), s.aliceStatic.Public.Y.Text(16), "y is not equal")
+	s.NoError(ValidateFFCPublicKeyFull(s.aliceStatic.Public), "public key rejected")
+}
+
+func (s *FFCTestSuite) TestNewPrivateKey() {
+	_, err := NewFFCPrivateKey(s.Params, s.aliceStatic.Private.X)
+	s.NoError(err, "valid private key rejected")
+
+	_, err = NewFFCPrivateKey(s.Params, []byte{0})
+	s.Error(err, "zero private key accepted")
+
+	_, err = NewFFCPrivateKey(s.Params, s.Params.Q.Bytes())
+	s.Error(err, "private key q accepted")
+
+	_, err = NewFFCPrivateKey(s.Params, make([]byte, len(s.Params.Q.Bytes())+1))
+	s.Error(err, "oversized private key accepted")
+}
+
+func (s *FFCTestSuite) TestReference() {
+	alice, bob := s.aliceStatic, s.bobStatic
+	aliceEphemeral, bobEphemeral := s.aliceEphemeral, s.bobEphemeral
+
+	ref := refFFCMQV(new(big.Int).SetBytes(alice.Private.X), new(big.Int).SetBytes(aliceEphemeral.Private.X),
+		aliceEphemeral.Public.Y, bob.Public.Y, bobEphemeral.Public.Y, s.Params)
+	z, err := FFCMQV(alice.Private.X, aliceEphemeral.Private.X, aliceEphemeral.Public.Y,
+		bob.Public.Y, bobEphemeral.Public.Y, s.Params)
+	s.Require().NoError(err, "failed to run ffc mqv")
+	s.Equal(ref.Text(16), z.Text(16), "ffc mqv differs from reference")
+
+	z, err = BlindFFCMQV(alice.Private.X, aliceEphemeral.Private.X, aliceEphemeral.Public.Y,
+		bob.Public.Y, bobEphemeral.Public.Y, s.Params, rand.Reader)
+	s.Require().NoError(err, "failed to run blind ffc mqv")
+	s.Equal(ref.Text(16), z.Text(16), "blind ffc mqv differs from reference")
+}
+
+func (s *FFCTestSuite) TestAgree() {
+	alice, err := FFCAgree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run agree for alice")
+	defer alice.Destroy()
+	bob, err := BlindFFCAgree(s.bobStatic, s.bobEphemeral, s.aliceStatic.Public, s.aliceEphemeral.Public, rand.Reader)
+	s.Require().NoError(err, "failed to run blind agree for bob")
+	defer bob.Destroy()
+
+	s.True(alice.Equal(bob), "shared secrets are not equal")
+	s.Len(alice.Bytes(), (s.Params.P.BitLen()+7)/8, "wrong length of shared secret")
+
+	kdf := &OneStepKDF{Hash: sha256.New}
+	keyU, err := kdf.DeriveKey(alice.Bytes(), []byte("info"), 32)
+	s.Require().NoError(err, "failed to derive key for alice")
+	keyV, err := kdf.DeriveKey(bob.Bytes(), []byte("info"), 32)
+	s.Require().NoError(err, "failed to derive key for bob")
+	s.Equal(keyU, keyV, "derived keys are not equal")
+}
+
+func (s *FFCTestSuite) TestOnePass() {
+	// MQV1: bob only has a static key, which is used as ephemeral key
+	alice, err := FFCAgree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, s.bobStatic.Public)
+	s.Require().NoError(err, "failed to run agree for alice")
+	bob, err := FFCAgree(s.bobStatic, s.bobStatic, s.aliceStatic.Public, s.aliceEphemeral.Public)
+	s.Require().NoError(err, "failed to run agree for bob")
+	s.True(alice.Equal(bob), "shared secrets are not equal")
+
+	other := s.generateKeyPair("other")
+	_, err = FFCAgree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, &FFCPublicKey{Params: &FFCParams{}, Y: other.Public.Y})
+	s.Error(err, "keys of different groups accepted")
+}
+
+func (s *FFCTestSuite) TestValidatePublicKey() {
+	p := s.Params.P
+	for name, y := range map[string]*big.Int{
+		"zero":  big.NewInt(0),
+		"one":   big.NewInt(1),
+		"p - 1": new(big.Int).Sub(p, one),
+		"p":     new(big.Int).Set(p),
+		"p + 2": new(big.Int).Add(p, big.NewInt(2)),
+	} {
+		pub := &FFCPublicKey{Params: s.Params, Y: y}
+		err := ValidateFFCPublicKeyPartial(pub)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "%s accepted by partial validation: %v", name, err)
+		err = ValidateFFCPublicKeyFull(pub)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "%s accepted by full validation: %v", name, err)
+	}
+
+	// -y is not in the subgroup of order q, since q is odd
+	neg := &FFCPublicKey{Params: s.Params, Y: new(big.Int).Sub(p, s.bobStatic.Public.Y)}
+	s.NoError(ValidateFFCPublicKeyPartial(neg), "partial validation rejected element in range")
+	err := ValidateFFCPublicKeyFull(neg)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "element outside of the subgroup accepted: %v", err)
+
+	s.Error(ValidateFFCPublicKeyFull(nil), "missing key accepted")
+	s.Error(ValidateFFCPublicKeyFull(&FFCPublicKey{Params: s.Params}), "missing y accepted")
+}
+
+func (s *FFCTestSuite) TestIdentity() {
+	kp := s.aliceStatic
+	_, err := FFCMQV(kp.Private.X, kp.Private.X, kp.Public.Y, one, one, s.Params)
+	s.Equal(ErrIdentity, err, "identity not detected")
+	_, err = BlindFFCMQV(kp.Private.X, kp.Private.X, kp.Public.Y, one, one, s.Params, rand.Reader)
+	s.Equal(ErrIdentity, err, "identity not detected")
+}
+
+func TestFFCDHE2048(t *testing.T) {
+	suite.Run(t, &FFCTestSuite{Params: FFDHE2048()})
+}
+
+func TestFFCMODP2048(t *testing.T) {
+	suite.Run(t, &FFCTestSuite{Params: MODP2048()})
+}
+
+func TestFFCFIPS186(t *testing.T) {
+	suite.Run(t, &FFCTestSuite{Params: testFFC2048()})
+}
+
+func TestSafePrimeGroups(t *testing.T) {
+	for _, params := range safePrimeGroups() {
+		p, q := params.P, params.Q
+		if new(big.Int).Add(new(big.Int).Lsh(q, 1), one).Cmp(p) != 0 {
+			t.Errorf("%s: p is not 2q + 1", params.Name)
+		}
+		h := p.Text(16)
+		if h[:16] != "ffffffffffffffff" || h[len(h)-16:] != "ffffffffffffffff" {
+			t.Errorf("%s: p does not start and end with 64 one bits", params.Name)
+		}
+		if new(big.Int).Exp(params.G, q, p).Cmp(one) != 0 {
+			t.Errorf("%s: generator is not in the subgroup of order q", params.Name)
+		}
+		if testing.Short() && p.BitLen() > 2048 {
+			continue
+		}
+		if !p.ProbablyPrime(0) || !q.ProbablyPrime(0) {
+			t.Errorf("%s: p or q is not prime", params.Name)
+		}
+	}
+}
+
+func TestValidateFFCParams(t *testing.T) {
+	valid := testFFC2048()
+	modify := func(f func(params *FFCParams)) *FFCParams {
+		params := *valid
+		f(&params)
+		return &params
+	}
+	for name, params := range map[string]*FFCParams{
+		"missing":          nil,
+		"missing p":        modify(func(params *FFCParams) { params.P = nil }),
+		"composite p":      modify(func(params *FFCParams) { params.P = new(big.Int).Add(valid.P, big.NewInt(2)) }),
+		"composite q":      modify(func(params *FFCParams) { params.Q = new(big.Int).Add(valid.Q, big.NewInt(2)) }),
+		"q not dividing":   modify(func(params *FFCParams) { params.Q = big.NewInt(0).SetBytes(FFDHE2048().Q.Bytes()[:28]) }),
+		"small p":          modify(func(params *FFCParams) { params.P = big.NewInt(23) }),
+		"generator one":    modify(func(params *FFCParams) { params.G = big.NewInt(1) }),
+		"generator p - 1":  modify(func(params *FFCParams) { params.G = new(big.Int).Sub(valid.P, one) }),
+		"generator order":  modify(func(params *FFCParams) { params.G = big.NewInt(3) }),
+		"wrong safe prime": modify(func(params *FFCParams) { *params = *MODP2048(); params.G = new(big.Int).Sub(params.P, big.NewInt(2)) }),
+	} {
+		if err := ValidateFFCParams(params); !errors.Is(err, ErrInvalidParameters) {
+			t.Errorf("invalid parameters %q accepted: %v", name, err)
+		}
+	}
+
+	if _, err := FFCMQV([]byte{1}, []byte{1}, one, one, one, &FFCParams{P: big.NewInt(8), Q: one, G: one}); !errors.Is(err, ErrInvalidParameters) {
+		t.Errorf("even modulus accepted: %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 module github.com/mgit-at/mqv
 
-go 1.12
+go 1.13
 
 require (
-	github.com/pkg/errors v0.8.1
 	github.com/stretchr/testify v1.3.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )
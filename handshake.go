@@ -0,0 +1,371 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// handshakeVersion is the version of the handshake messages.
+const handshakeVersion = 1
+
+// Types of the handshake messages.
+const (
+	msgInit     = 1
+	msgResponse = 2
+	msgConfirm  = 3
+)
+
+// handshakeState is the state of a handshake.
+type handshakeState int
+
+const (
+	stateStart handshakeState = iota
+	stateWaitResponse
+	stateWaitConfirm
+	stateDone
+	stateFailed
+)
+
+var errHandshakeState = errors.New("unexpected handshake state")
+
+// HandshakeConfig contains the parameters of a handshake. The SchemeConfig
+// must be the same for both parties. If Confirmation is set, party V (the
+// responder) provides a MacTag in its response and, if Bilateral is set,
+// party U (the initiator) in a third message.
+type HandshakeConfig struct {
+	SchemeConfig
+
+	// StaticKey is the own static key pair.
+	StaticKey *KeyPair
+
+	// PeerStaticKey is the static public key of the other party.
+	PeerStaticKey *PublicKey
+
+	// ID and PeerID are the identifiers of the own party and of the other
+	// party. If they are nil, the encoded static public keys are used
+	// instead (see MarshalPublicKey).
+	ID     []byte
+	PeerID []byte
+}
+
+// handshake contains the state shared by Initiator and Responder.
+type handshake struct {
+	config    HandshakeConfig
+	role      Role
+	idU, idV  []byte
+	pointLen  int
+	state     handshakeState
+	ephemeral *KeyPair
+	key       []byte
+	conf      *Confirmation
+}
+
+func newHandshake(config *HandshakeConfig, role Role) (*handshake, error) {
+	if config == nil {
+		return nil, errors.New("missing handshake config")
+	}
+	static := config.StaticKey
+	if _, err := checkCurves([]*KeyPair{static}, []*PublicKey{config.PeerStaticKey}); err != nil {
+		return nil, err
+	}
+	if err := ValidatePublicKeyFull(config.PeerStaticKey); err != nil {
+		return nil, fmt.Errorf("failed to validate static key: %w", err)
+	}
+	if config.KDF == nil {
+		return nil, errors.New("missing key-derivation function")
+	}
+	if config.Confirmation != nil {
+		if err := config.Confirmation.check(); err != nil {
+			return nil, err
+		}
+	}
+	ci, err := LookupCurve(static.Public.Curve)
+	if err != nil {
+		return nil, err
+	}
+
+	id, peerID := config.ID, config.PeerID
+	if id == nil {
+		if id, err = MarshalPublicKey(static.Public); err != nil {
+			return nil, err
+		}
+	}
+	if peerID == nil {
+		if peerID, err = MarshalPublicKey(config.PeerStaticKey); err != nil {
+			return nil, err
+		}
+	}
+	h := &handshake{
+		config:   *config,
+		role:     role,
+		idU:      id,
+		idV:      peerID,
+		pointLen: 1 + 2*ci.FieldBytes(),
+	}
+	if role == PartyV {
+		h.idU, h.idV = h.idV, h.idU
+	}
+	return h, nil
+}
+
+// fail aborts the handshake and wipes all secrets.
+func (h *handshake) fail(err error) error {
+	h.state = stateFailed
+	h.destroy()
+	return err
+}
+
+// destroy wipes the ephemeral private key, the MacKey and the session key.
+func (h *handshake) destroy() {
+	if h.ephemeral != nil {
+		WipeBytes(h.ephemeral.Private.D)
+		h.ephemeral = nil
+	}
+	if h.conf != nil {
+		h.conf.Destroy()
+		h.conf = nil
+	}
+	WipeBytes(h.key)
+	h.key = nil
+}
+
+// generate creates the ephemeral key pair and returns the handshake message
+// of the given type with the ephemeral public key.
+func (h *handshake) generate(typ byte) ([]byte, error) {
+	var err error
+	h.ephemeral, err = GenerateKeyPair(h.config.StaticKey.Public.Curve, h.config.rand())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	point, err := MarshalPublicKey(h.ephemeral.Public)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{handshakeVersion, typ}, point...), nil
+}
+
+// parse checks the header of a handshake message of the given type and
+// returns its payload, which must have the given length.
+func (h *handshake) parse(msg []byte, typ byte, length int) ([]byte, error) {
+	if len(msg) < 2 || msg[0] != handshakeVersion || msg[1] != typ {
+		return nil, fmt.Errorf("%w: unexpected handshake message", ErrInvalidMessage)
+	}
+	if len(msg) != 2+length {
+		return nil, fmt.Errorf("%w: invalid length of handshake message", ErrInvalidMessage)
+	}
+	return msg[2:], nil
+}
+
+// derive calculates the session key and the key confirmation from the
+// ephemeral public key of the other party. The ephemeral private key is
+// wiped afterwards.
+func (h *handshake) derive(point []byte) error {
+	defer func() {
+		WipeBytes(h.ephemeral.Private.D)
+		h.ephemeral = nil
+	}()
+	otherEphemeral, err := UnmarshalPublicKey(h.config.StaticKey.Public.Curve, point)
+	if err != nil {
+		return fmt.Errorf("failed to decode ephemeral key: %w", err)
+	}
+	if equalPublicKeys(otherEphemeral, h.ephemeral.Public) {
+		return fmt.Errorf("%w: reflected ephemeral key", ErrInvalidPublicKey)
+	}
+
+	scheme := &FullMQV{h.config.SchemeConfig}
+	if h.config.Confirmation == nil {
+		h.key, err = scheme.DeriveKey(h.idU, h.idV, h.config.StaticKey, h.ephemeral, h.config.PeerStaticKey, otherEphemeral)
+		return err
+	}
+	h.key, h.conf, err = scheme.ConfirmKey(h.role, h.idU, h.idV, h.config.StaticKey, h.ephemeral, h.config.PeerStaticKey, otherEphemeral)
+	return err
+}
+
+// tagLen returns the length of the MacTag, or 0 without key confirmation.
+func (h *handshake) tagLen() int {
+	if h.config.Confirmation == nil {
+		return 0
+	}
+	return h.config.Confirmation.TagLen
+}
+
+// sessionKey returns the session key of a completed handshake.
+func (h *handshake) sessionKey() ([]byte, error) {
+	if h.state != stateDone {
+		return nil, errHandshakeState
+	}
+	return h.key, nil
+}
+
+// Initiator runs the handshake of party U of the full MQV scheme
+// C(2e, 2s), see FullMQV. It sends the first message with Start and
+// processes the response of the Responder with Finish. The ephemeral key is
+// generated by the Initiator and wiped as soon as the shared secret has been
+// calculated. The methods must be called in order and every method only
+// once. After an error, the handshake is aborted and can not be resumed.
+type Initiator struct {
+	h *handshake
+}
+
+// NewInitiator returns an Initiator for the given config. The static public
+// key of the other party is validated fully.
+func NewInitiator(config *HandshakeConfig) (*Initiator, error) {
+	h, err := newHandshake(config, PartyU)
+	if err != nil {
+		return nil, err
+	}
+	return &Initiator{h: h}, nil
+}
+
+// Start generates the ephemeral key pair and returns the first handshake
+// message, which has to be sent to the Responder.
+func (i *Initiator) Start() ([]byte, error) {
+	h := i.h
+	if h.state != stateStart {
+		return nil, h.fail(errHandshakeState)
+	}
+	msg, err := h.generate(msgInit)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+	h.state = stateWaitResponse
+	return msg, nil
+}
+
+// Finish processes the response of the Responder and derives the session
+// key. With key confirmation, the MacTag of the Responder is verified. With
+// bilateral key confirmation, the returned message contains the MacTag of
+// the Initiator and has to be sent to the Responder, otherwise it is nil.
+func (i *Initiator) Finish(response []byte) ([]byte, error) {
+	h := i.h
+	if h.state != stateWaitResponse {
+		return nil, h.fail(errHandshakeState)
+	}
+	payload, err := h.parse(response, msgResponse, h.pointLen+h.tagLen())
+	if err != nil {
+		return nil, h.fail(err)
+	}
+	if err := h.derive(payload[:h.pointLen]); err != nil {
+		return nil, h.fail(err)
+	}
+
+	var msg []byte
+	if h.conf != nil {
+		if err := h.conf.Verify(payload[h.pointLen:], nil); err != nil {
+			return nil, h.fail(err)
+		}
+		if h.config.Confirmation.Bilateral {
+			tag, err := h.conf.Tag(nil)
+			if err != nil {
+				return nil, h.fail(err)
+			}
+			msg = append([]byte{handshakeVersion, msgConfirm}, tag...)
+		}
+		h.conf.Destroy()
+		h.conf = nil
+	}
+	h.state = stateDone
+	return msg, nil
+}
+
+// SessionKey returns the derived keying material after the handshake has
+// been completed.
+func (i *Initiator) SessionKey() ([]byte, error) {
+	return i.h.sessionKey()
+}
+
+// Destroy wipes the secrets of the handshake, including the session key.
+func (i *Initiator) Destroy() {
+	i.h.fail(nil)
+}
+
+// Responder runs the handshake of party V of the full MQV scheme
+// C(2e, 2s), see FullMQV and Initiator. It processes the first message of
+// the Initiator with Respond and, with bilateral key confirmation, the
+// MacTag of the Initiator with Finish.
+type Responder struct {
+	h *handshake
+}
+
+// NewResponder returns a Responder for the given config. The static public
+// key of the other party is validated fully.
+func NewResponder(config *HandshakeConfig) (*Responder, error) {
+	h, err := newHandshake(config, PartyV)
+	if err != nil {
+		return nil, err
+	}
+	return &Responder{h: h}, nil
+}
+
+// Respond processes the first message of the Initiator, generates the
+// ephemeral key pair and derives the session key. The returned response has
+// to be sent to the Initiator and contains the ephemeral public key and,
+// with key confirmation, the MacTag of the Responder. Without bilateral key
+// confirmation, the handshake is completed afterwards.
+func (r *Responder) Respond(msg []byte) ([]byte, error) {
+	h := r.h
+	if h.state != stateStart {
+		return nil, h.fail(errHandshakeState)
+	}
+	payload, err := h.parse(msg, msgInit, h.pointLen)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+	response, err := h.generate(msgResponse)
+	if err != nil {
+		return nil, h.fail(err)
+	}
+	if err := h.derive(payload); err != nil {
+		return nil, h.fail(err)
+	}
+
+	h.state = stateDone
+	if h.conf != nil {
+		tag, err := h.conf.Tag(nil)
+		if err != nil {
+			return nil, h.fail(err)
+		}
+		response = append(response, tag...)
+		if h.config.Confirmation.Bilateral {
+			h.state = stateWaitConfirm
+		} else {
+			h.conf.Destroy()
+			h.conf = nil
+		}
+	}
+	return response, nil
+}
+
+// Finish verifies the MacTag of the Initiator with bilateral key
+// confirmation, which completes the handshake.
+func (r *Responder) Finish(msg []byte) error {
+	h := r.h
+	if h.state != stateWaitConfirm {
+		return h.fail(errHandshakeState)
+	}
+	tag, err := h.parse(msg, msgConfirm, h.tagLen())
+	if err != nil {
+		return h.fail(err)
+	}
+	if err := h.conf.Verify(tag, nil); err != nil {
+		return h.fail(err)
+	}
+	h.conf.Destroy()
+	h.conf = nil
+	h.state = stateDone
+	return nil
+}
+
+// SessionKey returns the derived keying material after the handshake has
+// been completed.
+func (r *Responder) SessionKey() ([]byte, error) {
+	return r.h.sessionKey()
+}
+
+// Destroy wipes the secrets of the handshake, including the session key.
+func (r *Responder) Destroy() {
+	r.h.fail(nil)
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type HandshakeTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	scheme SchemeConfig
+	alice  *KeyPair
+	bob    *KeyPair
+}
+
+func (s *HandshakeTestSuite) SetupTest() {
+	s.scheme = SchemeConfig{
+		KDF:         &OneStepKDF{Hash: sha256.New},
+		KeyLen:      32,
+		AlgorithmID: []byte("AES-256"),
+	}
+	var err error
+	s.alice, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair for alice")
+	s.bob, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair for bob")
+}
+
+func (s *HandshakeTestSuite) parties(scheme SchemeConfig) (*Initiator, *Responder) {
+	initiator, err := NewInitiator(&HandshakeConfig{SchemeConfig: scheme, StaticKey: s.alice, PeerStaticKey: s.bob.Public})
+	s.Require().NoError(err, "failed to create initiator")
+	responder, err := NewResponder(&HandshakeConfig{SchemeConfig: scheme, StaticKey: s.bob, PeerStaticKey: s.alice.Public})
+	s.Require().NoError(err, "failed to create responder")
+	return initiator, responder
+}
+
+func (s *HandshakeTestSuite) confirmation(bilateral bool) SchemeConfig {
+	scheme := s.scheme
+	scheme.Confirmation = &KeyConfirmation{
+		MAC:       &HMAC{Hash: sha256.New},
+		MacKeyLen: 32,
+		TagLen:    16,
+		Bilateral: bilateral,
+	}
+	return scheme
+}
+
+func (s *HandshakeTestSuite) TestHandshake() {
+	initiator, responder := s.parties(s.scheme)
+
+	msg1, err := initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	_, err = initiator.SessionKey()
+	s.Error(err, "session key before the end of the handshake")
+
+	msg2, err := responder.Respond(msg1)
+	s.Require().NoError(err, "failed to respond")
+	msg3, err := initiator.Finish(msg2)
+	s.Require().NoError(err, "failed to finish")
+	s.Nil(msg3, "unexpected third message")
+
+	keyU, err := initiator.SessionKey()
+	s.Require().NoError(err, "missing session key of the initiator")
+	keyV, err := responder.SessionKey()
+	s.Require().NoError(err, "missing session key of the responder")
+	s.Len(keyU, s.scheme.KeyLen, "invalid key length")
+	s.Equal(keyU, keyV, "session keys are not equal")
+	s.Nil(initiator.h.ephemeral, "ephemeral key of the initiator not wiped")
+	s.Nil(responder.h.ephemeral, "ephemeral key of the responder not wiped")
+
+	initiator.Destroy()
+	_, err = initiator.SessionKey()
+	s.Error(err, "session key after destroy")
+	s.Equal(make([]byte, len(keyU)), keyU, "session key not wiped")
+}
+
+func (s *HandshakeTestSuite) TestConfirmation() {
+	for _, bilateral := range []bool{false, true} {
+		initiator, responder := s.parties(s.confirmation(bilateral))
+
+		msg1, err := initiator.Start()
+		s.Require().NoError(err, "failed to start")
+		msg2, err := responder.Respond(msg1)
+		s.Require().NoError(err, "failed to respond")
+		msg3, err := initiator.Finish(msg2)
+		s.Require().NoError(err, "failed to finish")
+
+		if bilateral {
+			s.NotNil(msg3, "missing third message")
+			_, err = responder.SessionKey()
+			s.Error(err, "session key before key confirmation")
+			s.Require().NoError(responder.Finish(msg3), "failed to confirm")
+		} else {
+			s.Nil(msg3, "unexpected third message")
+			s.Error(responder.Finish(nil), "unexpected key confirmation accepted")
+			continue
+		}
+
+		keyU, err := initiator.SessionKey()
+		s.Require().NoError(err, "missing session key of the initiator")
+		keyV, err := responder.SessionKey()
+		s.Require().NoError(err, "missing session key of the responder")
+		s.Equal(keyU, keyV, "session keys are not equal")
+	}
+}
+
+func (s *HandshakeTestSuite) TestInvalidConfirmation() {
+	initiator, responder := s.parties(s.confirmation(true))
+	msg1, err := initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	msg2, err := responder.Respond(msg1)
+	s.Require().NoError(err, "failed to respond")
+
+	modified := append([]byte(nil), msg2...)
+	modified[len(modified)-1] ^= 1
+	_, err = initiator.Finish(modified)
+	s.True(errors.Is(err, ErrAuthentication), "invalid tag of the responder accepted: %v", err)
+	_, err = initiator.Finish(msg2)
+	s.Error(err, "handshake resumed after an error")
+
+	initiator, responder = s.parties(s.confirmation(true))
+	msg1, err = initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	msg2, err = responder.Respond(msg1)
+	s.Require().NoError(err, "failed to respond")
+	msg3, err := initiator.Finish(msg2)
+	s.Require().NoError(err, "failed to finish")
+	msg3[len(msg3)-1] ^= 1
+	err = responder.Finish(msg3)
+	s.True(errors.Is(err, ErrAuthentication), "invalid tag of the initiator accepted: %v", err)
+	_, err = responder.SessionKey()
+	s.Error(err, "session key after failed key confirmation")
+}
+
+func (s *HandshakeTestSuite) TestStateTransitions() {
+	initiator, responder := s.parties(s.scheme)
+
+	_, err := initiator.Finish(nil)
+	s.Error(err, "finish before start accepted")
+	_, err = initiator.Start()
+	s.Error(err, "start after an error accepted")
+
+	initiator, _ = s.parties(s.scheme)
+	msg1, err := initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	_, err = initiator.Start()
+	s.Error(err, "second start accepted")
+
+	_, err = responder.Respond(msg1[:len(msg1)-1])
+	s.True(errors.Is(err, ErrInvalidMessage), "truncated message accepted: %v", err)
+
+	_, responder = s.parties(s.scheme)
+	_, err = responder.Respond(msg1)
+	s.Require().NoError(err, "failed to respond")
+	_, err = responder.Respond(msg1)
+	s.Error(err, "second response accepted")
+	_, err = responder.SessionKey()
+	s.Error(err, "session key after an error")
+
+	initiator, _ = s.parties(s.scheme)
+	msg1, err = initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	_, err = initiator.Finish(msg1)
+	s.True(errors.Is(err, ErrInvalidMessage), "own message accepted as response: %v", err)
+
+	// without key confirmation, the response of another handshake results
+	// in a different session key
+	other, responder := s.parties(s.scheme)
+	msg1, err = other.Start()
+	s.Require().NoError(err, "failed to start")
+	msg2, err := responder.Respond(msg1)
+	s.Require().NoError(err, "failed to respond")
+	initiator, _ = s.parties(s.scheme)
+	_, err = initiator.Start()
+	s.Require().NoError(err, "failed to start")
+	_, err = initiator.Finish(msg2)
+	s.Require().NoError(err, "failed to finish")
+	keyU, err := initiator.SessionKey()
+	s.Require().NoError(err, "missing session key of the initiator")
+	keyV, err := responder.SessionKey()
+	s.Require().NoError(err, "missing session key of the responder")
+	s.NotEqual(keyU, keyV, "response of another handshake results in the same key")
+}
+
+func (s *HandshakeTestSuite) TestReflection() {
+	initiator, _ := s.parties(s.scheme)
+	msg1, err := initiator.Start()
+	s.Require().NoError(err, "failed to start")
+
+	reflected := append([]byte(nil), msg1...)
+	reflected[1] = msgResponse
+	_, err = initiator.Finish(reflected)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "reflected ephemeral key accepted: %v", err)
+}
+
+func (s *HandshakeTestSuite) TestInvalidConfig() {
+	_, err := NewInitiator(nil)
+	s.Error(err, "missing config accepted")
+	_, err = NewInitiator(&HandshakeConfig{SchemeConfig: s.scheme, StaticKey: s.alice})
+	s.Error(err, "missing peer key accepted")
+	_, err = NewResponder(&HandshakeConfig{SchemeConfig: s.scheme, PeerStaticKey: s.alice.Public})
+	s.Error(err, "missing static key accepted")
+	_, err = NewInitiator(&HandshakeConfig{StaticKey: s.alice, PeerStaticKey: s.bob.Public})
+	s.Error(err, "missing kdf accepted")
+
+	invalid := &PublicKey{Curve: s.Curve, X: s.bob.Public.X, Y: s.bob.Public.X}
+	_, err = NewInitiator(&HandshakeConfig{SchemeConfig: s.scheme, StaticKey: s.alice, PeerStaticKey: invalid})
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid peer key accepted: %v", err)
+}
+
+func TestHandshakeP224(t *testing.T) {
+	suite.Run(t, &HandshakeTestSuite{Curve: elliptic.P224()})
+}
+
+func TestHandshakeP256(t *testing.T) {
+	suite.Run(t, &HandshakeTestSuite{Curve: elliptic.P256()})
+}
+
+func TestHandshakeP384(t *testing.T) {
+	suite.Run(t, &HandshakeTestSuite{Curve: elliptic.P384()})
+}
+
+func TestHandshakeP521(t *testing.T) {
+	suite.Run(t, &HandshakeTestSuite{Curve: elliptic.P521()})
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+)
+
+// hmqvHash calculates the hash value of the HMQV family. The fields are
+// prefixed by their length as a 32-bit big-endian integer and hashed with
+// h. The result is truncated to the leftmost ceil(f/2) bits with
+// f = ceil(log2(n)), which is the length used by avf.
+func hmqvHash(h func() hash.Hash, n *big.Int, fields ...[]byte) *big.Int {
+	d := h()
+	for _, f := range fields {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(f)))
+		d.Write(l[:])
+		d.Write(f)
+	}
+	digest := d.Sum(nil)
+
+	r := new(big.Int).SetBytes(digest)
+	if excess := 8*len(digest) - (n.BitLen()+1)/2; excess > 0 {
+		r.Rsh(r, uint(excess))
+	}
+	return r
+}
+
+// hmqvPeers returns the identifiers and the encoded ephemeral public keys of
+// the own party and the other party.
+func hmqvPeers(role Role, idU, idV []byte, ownEphemeral *KeyPair, otherEphemeral *PublicKey) (ownID, otherID, ownEphem, otherEphem []byte, err error) {
+	ownEphem, err = MarshalPublicKey(ownEphemeral.Public)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	otherEphem, err = MarshalPublicKey(otherEphemeral)
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+	switch role {
+	case PartyU:
+		return idU, idV, ownEphem, otherEphem, nil
+	case PartyV:
+		return idV, idU, ownEphem, otherEphem, nil
+	}
+	return nil, nil, nil, nil, fmt.Errorf("invalid role %d", role)
+}
+
+// hmqvFactors returns the factors d and e of the own and the other party's
+// implicit signatures. For HMQV (full = false) they are d = H(X, B) and
+// e = H(Y, A), where X is the own and Y the other party's ephemeral public
+// key and A and B are the identifiers of the own and the other party. For
+// FHMQV (full = true) they are d = H(X, Y, A, B) and e = H(Y, X, A, B),
+// where X is the ephemeral public key and A the identifier of party U.
+func hmqvFactors(h func() hash.Hash, full bool, role Role, idU, idV []byte, ownEphemeral *KeyPair, otherEphemeral *PublicKey, n *big.Int) (*big.Int, *big.Int, error) {
+	if h == nil {
+		return nil, nil, errors.New("missing hash function")
+	}
+	ownID, otherID, ownEphem, otherEphem, err := hmqvPeers(role, idU, idV, ownEphemeral, otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !full {
+		return hmqvHash(h, n, ownEphem, otherID), hmqvHash(h, n, otherEphem, ownID), nil
+	}
+	ephemU, ephemV := ownEphem, otherEphem
+	if role == PartyV {
+		ephemU, ephemV = ephemV, ephemU
+	}
+	d := hmqvHash(h, n, ephemU, ephemV, idU, idV)
+	e := hmqvHash(h, n, ephemV, ephemU, idU, idV)
+	if role == PartyV {
+		d, e = e, d
+	}
+	return d, e, nil
+}
+
+// hmqv calculates the shared secret of the HMQV family, optionally blinded.
+func hmqv(h func() hash.Hash, full bool, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey, blind bool, rand io.Reader, modes []BlindMode) (*SharedSecret, error) {
+	curve, err := checkCurves([]*KeyPair{ownStatic, ownEphemeral}, []*PublicKey{otherStatic, otherEphemeral})
+	if err != nil {
+		return nil, err
+	}
+	info, err := LookupCurve(curve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up curve: %w", err)
+	}
+	curve, cofactor := info.Curve, info.Cofactor
+	n := curve.Params().N
+	if err := checkPeerRange(curve, otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y); err != nil {
+		return nil, err
+	}
+
+	d, e, err := hmqvFactors(h, full, role, idU, idV, ownEphemeral, otherEphemeral, n)
+	if err != nil {
+		return nil, err
+	}
+
+	bx, by := implicitBase(otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, e, curve)
+	defer WipeInt(bx)
+	defer WipeInt(by)
+
+	var x, y *big.Int
+	if blind {
+		x, y, err = blindImplicit(ownStatic.Private.D, ownEphemeral.Private.D, d, bx, by, curve, cofactor, rand, blindMode(modes))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		s := implicitSig(ownStatic.Private.D, ownEphemeral.Private.D, d, n, cofactor)
+		defer WipeBytes(s)
+		x, y = scalarMult(curve, bx, by, s)
+		if isInfinity(x, y) {
+			return nil, ErrIdentity
+		}
+	}
+	defer WipeInt(x)
+	defer WipeInt(y)
+	return NewSharedSecret(x, curve)
+}
+
+// HMQV implements the HMQV primitive of Krawczyk, which calculates the shared
+// secret Z = h * (x + d * a) * (Y + e * B) with the own static and ephemeral
+// private keys a and x and the other party's static and ephemeral public
+// keys B and Y. Unlike MQV, the factors d = H(X, idB) and e = H(Y, idA) bind
+// the ephemeral public keys X and Y to the identifiers idA and idB of the
+// own and the other party, which prevents key-compromise impersonation
+// attacks. H is the given hash function truncated to ceil(f/2) bits with
+// f = ceil(log2(n)).
+//
+// Party U is the initiator and party V the responder of the key agreement.
+// Both parties pass the identifiers in the same order and their own role.
+// The keys are the same as for MQV and Agree. The public keys of the other
+// party are not validated by this primitive, see ValidatePublicKeyFull and
+// ValidatePublicKeyPartial. Only coordinates outside of the range [0, p-1]
+// are rejected with ErrInvalidPublicKey. ErrIdentity is returned if the
+// shared secret is the point at infinity.
+func HMQV(h func() hash.Hash, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error) {
+	return hmqv(h, false, role, idU, idV, ownStatic, ownEphemeral, otherStatic, otherEphemeral, false, nil, nil)
+}
+
+// BlindHMQV is similar to HMQV, but blinds the private keys like BlindMQV.
+// Additional countermeasures can be selected by modes.
+func BlindHMQV(h func() hash.Hash, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey, rand io.Reader, modes ...BlindMode) (*SharedSecret, error) {
+	return hmqv(h, false, role, idU, idV, ownStatic, ownEphemeral, otherStatic, otherEphemeral, true, rand, modes)
+}
+
+// FHMQV implements the FHMQV primitive of Sarr, Elbaz-Vincent and Bajard.
+// It is similar to HMQV, but both factors depend on both ephemeral public
+// keys and both identifiers: d = H(X, Y, idU, idV) and e = H(Y, X, idU, idV),
+// where X is the ephemeral public key of party U and Y the one of party V.
+func FHMQV(h func() hash.Hash, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error) {
+	return hmqv(h, true, role, idU, idV, ownStatic, ownEphemeral, otherStatic, otherEphemeral, false, nil, nil)
+}
+
+// BlindFHMQV is similar to FHMQV, but blinds the private keys like
+// BlindMQV. Additional countermeasures can be selected by modes.
+func BlindFHMQV(h func() hash.Hash, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey, rand io.Reader, modes ...BlindMode) (*SharedSecret, error) {
+	return hmqv(h, true, role, idU, idV, ownStatic, ownEphemeral, otherStatic, otherEphemeral, true, rand, modes)
+}
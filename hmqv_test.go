@@ -0,0 +1,183 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// refHMQVHash hashes the length-prefixed fields and keeps the leftmost
+// ceil(f/2) bits.
+func refHMQVHash(h func() hash.Hash, n *big.Int, fields ...[]byte) *big.Int {
+	d := h()
+	for _, f := range fields {
+		l := make([]byte, 4)
+		binary.BigEndian.PutUint32(l, uint32(len(f)))
+		d.Write(append(l, f...))
+	}
+	digest := new(big.Int).SetBytes(d.Sum(nil))
+	l := (n.BitLen() + 1) / 2
+	if l < 8*d.Size() {
+		digest.Rsh(digest, uint(8*d.Size()-l))
+	}
+	return digest
+}
+
+// refHMQV is a straightforward implementation of the HMQV primitive for
+// party U: Z = h * (x + d * a) * (Y + e * B).
+func refHMQV(a, x []byte, bPub, yPub *PublicKey, d, e *big.Int, curve elliptic.Curve, h *big.Int) *big.Int {
+	n := curve.Params().N
+	s := new(big.Int).Mul(d, new(big.Int).SetBytes(a))
+	s.Add(s, new(big.Int).SetBytes(x))
+	s.Mod(s, n)
+	s.Mul(s, h)
+
+	ex, ey := curve.ScalarMult(bPub.X, bPub.Y, e.Bytes())
+	tx, ty := curve.Add(yPub.X, yPub.Y, ex, ey)
+	zx, _ := curve.ScalarMult(tx, ty, s.Bytes())
+	return zx
+}
+
+type HMQVTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	alice, aliceEphemeral, bob, bobEphemeral *KeyPair
+}
+
+func (s *HMQVTestSuite) SetupTest() {
+	kp := func() *KeyPair {
+		kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+		s.Require().NoError(err, "failed to create key pair")
+		return kp
+	}
+	s.alice, s.aliceEphemeral, s.bob, s.bobEphemeral = kp(), kp(), kp(), kp()
+}
+
+func (s *HMQVTestSuite) TestReference() {
+	h, err := cofactor(s.Curve)
+	s.Require().NoError(err, "curve not registered")
+	n := s.Curve.Params().N
+	idU, idV := []byte("alice"), []byte("bob")
+	x, err := MarshalPublicKey(s.aliceEphemeral.Public)
+	s.Require().NoError(err, "failed to marshal ephemeral key of alice")
+	y, err := MarshalPublicKey(s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to marshal ephemeral key of bob")
+
+	d, e := refHMQVHash(sha256.New, n, x, idV), refHMQVHash(sha256.New, n, y, idU)
+	ref := refHMQV(s.alice.Private.D, s.aliceEphemeral.Private.D, s.bob.Public, s.bobEphemeral.Public,
+		d, e, s.Curve, h)
+	z, err := HMQV(sha256.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run hmqv")
+	want, err := NewSharedSecret(ref, s.Curve)
+	s.Require().NoError(err, "failed to convert reference")
+	s.Equal(want.Bytes(), z.Bytes(), "hmqv differs from reference")
+
+	d, e = refHMQVHash(sha256.New, n, x, y, idU, idV), refHMQVHash(sha256.New, n, y, x, idU, idV)
+	ref = refHMQV(s.alice.Private.D, s.aliceEphemeral.Private.D, s.bob.Public, s.bobEphemeral.Public,
+		d, e, s.Curve, h)
+	z, err = FHMQV(sha256.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run fhmqv")
+	want, err = NewSharedSecret(ref, s.Curve)
+	s.Require().NoError(err, "failed to convert reference")
+	s.Equal(want.Bytes(), z.Bytes(), "fhmqv differs from reference")
+}
+
+func (s *HMQVTestSuite) TestAgree() {
+	idU, idV := []byte("alice"), []byte("bob")
+	for name, f := range map[string]func(h func() hash.Hash, role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error){
+		"hmqv":  HMQV,
+		"fhmqv": FHMQV,
+	} {
+		zU, err := f(sha512.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+		s.Require().NoErrorf(err, "failed to run %s for alice", name)
+		zV, err := f(sha512.New, PartyV, idU, idV, s.bob, s.bobEphemeral, s.alice.Public, s.aliceEphemeral.Public)
+		s.Require().NoErrorf(err, "failed to run %s for bob", name)
+		s.Truef(zU.Equal(zV), "shared secrets of %s are not equal", name)
+
+		zV, err = f(sha512.New, PartyV, idU, []byte("mallory"), s.bob, s.bobEphemeral, s.alice.Public, s.aliceEphemeral.Public)
+		s.Require().NoErrorf(err, "failed to run %s with different identifier", name)
+		s.Falsef(zU.Equal(zV), "identifiers are not bound to the shared secret of %s", name)
+
+		_, err = f(sha512.New, Role(2), idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+		s.Errorf(err, "invalid role accepted by %s", name)
+		_, err = f(nil, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+		s.Errorf(err, "missing hash function accepted by %s", name)
+	}
+
+	z, err := Agree(s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run mqv")
+	zH, err := HMQV(sha256.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run hmqv")
+	s.False(z.Equal(zH), "hmqv equals mqv")
+}
+
+func (s *HMQVTestSuite) TestBlind() {
+	idU, idV := []byte("alice"), []byte("bob")
+	z, err := HMQV(sha256.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public)
+	s.Require().NoError(err, "failed to run hmqv")
+	zF, err := FHMQV(sha256.New, PartyV, idU, idV, s.bob, s.bobEphemeral, s.alice.Public, s.aliceEphemeral.Public)
+	s.Require().NoError(err, "failed to run fhmqv")
+
+	for _, mode := range []BlindMode{0, BlindMultiplicative, BlindPoint} {
+		b, err := BlindHMQV(sha256.New, PartyU, idU, idV, s.alice, s.aliceEphemeral, s.bob.Public, s.bobEphemeral.Public, rand.Reader, mode)
+		s.Require().NoErrorf(err, "failed to run blind hmqv with mode %d", mode)
+		s.Truef(z.Equal(b), "blind hmqv differs for mode %d", mode)
+
+		b, err = BlindFHMQV(sha256.New, PartyV, idU, idV, s.bob, s.bobEphemeral, s.alice.Public, s.aliceEphemeral.Public, rand.Reader, mode)
+		s.Require().NoErrorf(err, "failed to run blind fhmqv with mode %d", mode)
+		s.Truef(zF.Equal(b), "blind fhmqv differs for mode %d", mode)
+	}
+}
+
+func TestHMQVP224(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: elliptic.P224()})
+}
+
+func TestHMQVP256(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: elliptic.P256()})
+}
+
+func TestHMQVP384(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: elliptic.P384()})
+}
+
+func TestHMQVP521(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: elliptic.P521()})
+}
+
+func TestHMQVBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: BrainpoolP256r1()})
+}
+
+func TestHMQVCofactor4(t *testing.T) {
+	suite.Run(t, &HMQVTestSuite{Curve: testCurveH4()})
+}
+
+func TestHMQVUnsupportedCurve(t *testing.T) {
+	params := *elliptic.P256().Params()
+	params.B = new(big.Int).Add(params.B, one)
+	p256, err := GenerateKeyPair(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+	kp := &KeyPair{
+		Private: &PrivateKey{Curve: &params, D: p256.Private.D},
+		Public:  &PublicKey{Curve: &params, X: p256.Public.X, Y: p256.Public.Y},
+	}
+	_, err = HMQV(sha256.New, PartyU, nil, nil, kp, kp, kp.Public, kp.Public)
+	if !errors.Is(err, ErrUnsupportedCurve) {
+		t.Errorf("unsupported curve accepted: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/hmac"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math"
+)
+
+// KDF derives keying material of the given length (in bytes) from a shared
+// secret Z and the FixedInfo that binds the key to the context of the key
+// agreement.
+type KDF interface {
+	DeriveKey(z, fixedInfo []byte, length int) ([]byte, error)
+}
+
+// OneStepKDF implements the one-step key-derivation function with a hash
+// function as auxiliary function H. The derived keying material is
+// H(1 || Z || FixedInfo) || H(2 || Z || FixedInfo) || ... truncated to
+// the requested length, where the counter is encoded as a 32-bit big-endian
+// integer. See section 4.1 of SP 800-56C Rev. 2 for more details.
+type OneStepKDF struct {
+	Hash func() hash.Hash
+}
+
+// DeriveKey implements the KDF interface.
+func (k *OneStepKDF) DeriveKey(z, fixedInfo []byte, length int) ([]byte, error) {
+	h := k.Hash()
+	if err := checkKeyLength(length, h.Size()); err != nil {
+		return nil, err
+	}
+	return counterMode(h, length, z, fixedInfo), nil
+}
+
+// OneStepHMACKDF implements the one-step key-derivation function with HMAC
+// as auxiliary function H. The derived keying material is calculated the same
+// way as for OneStepKDF, with H(x) = HMAC-hash(salt, x). If Salt is nil, a
+// byte string of zeros is used as salt. See section 4.1 of SP 800-56C Rev. 2
+// for more details.
+type OneStepHMACKDF struct {
+	Hash func() hash.Hash
+	Salt []byte
+}
+
+// DeriveKey implements the KDF interface.
+func (k *OneStepHMACKDF) DeriveKey(z, fixedInfo []byte, length int) ([]byte, error) {
+	h := hmac.New(k.Hash, k.Salt)
+	if err := checkKeyLength(length, h.Size()); err != nil {
+		return nil, err
+	}
+	return counterMode(h, length, z, fixedInfo), nil
+}
+
+// TwoStepKDF implements the extraction-then-expansion key-derivation
+// function with HMAC. The randomness extraction step calculates the key
+// derivation key KDK = HMAC-hash(salt, Z), which is then expanded using the
+// KDF in counter mode of SP 800-108 with HMAC-hash(KDK, i || FixedInfo) as
+// PRF, where the counter i is encoded as 32-bit big-endian integer. If Salt
+// is nil, a byte string of zeros is used as salt. See section 5 of
+// SP 800-56C Rev. 2 for more details.
+type TwoStepKDF struct {
+	Hash func() hash.Hash
+	Salt []byte
+}
+
+// DeriveKey implements the KDF interface.
+func (k *TwoStepKDF) DeriveKey(z, fixedInfo []byte, length int) ([]byte, error) {
+	extract := hmac.New(k.Hash, k.Salt)
+	if err := checkKeyLength(length, extract.Size()); err != nil {
+		return nil, err
+	}
+	extract.Write(z)
+	kdk := extract.Sum(nil)
+	defer WipeBytes(kdk)
+
+	expand := hmac.New(k.Hash, kdk)
+	return counterMode(expand, length, fixedInfo), nil
+}
+
+// checkKeyLength checks that length bytes can be derived using a counter
+// mode with the given block size.
+func checkKeyLength(length, blockSize int) error {
+	if length <= 0 {
+		return errors.New("invalid key length")
+	}
+	if uint64((length+blockSize-1)/blockSize) > math.MaxUint32 {
+		return errors.New("key length too large")
+	}
+	return nil
+}
+
+// counterMode returns h(1 || data...) || h(2 || data...) || ... truncated
+// to length bytes.
+func counterMode(h hash.Hash, length int, data ...[]byte) []byte {
+	var counter [4]byte
+	key := make([]byte, 0, length+h.Size())
+	for i := uint32(1); len(key) < length; i++ {
+		h.Reset()
+		binary.BigEndian.PutUint32(counter[:], i)
+		h.Write(counter[:])
+		for _, d := range data {
+			h.Write(d)
+		}
+		key = h.Sum(key)
+	}
+	WipeBytes(key[length:cap(key)])
+	return key[:length]
+}
+
+// FixedInfo contains the context-specific data that is bound to the derived
+// keying material. AlgorithmID indicates how the derived keying material will
+// be used, PartyUInfo and PartyVInfo contain public information about party U
+// and party V, e.g. their identifiers. The optional fields SuppPubInfo and
+// SuppPrivInfo contain additional mutually known public and private data.
+type FixedInfo struct {
+	AlgorithmID  []byte
+	PartyUInfo   []byte
+	PartyVInfo   []byte
+	SuppPubInfo  []byte
+	SuppPrivInfo []byte
+}
+
+// Bytes encodes the FixedInfo using the concatenation format of section
+// 5.8.2.1.1 of SP 800-56A Rev. 3. Each field is prefixed by its length as
+// a 32-bit big-endian integer. SuppPubInfo and SuppPrivInfo are omitted if
+// they are nil. If SuppPrivInfo is present, SuppPubInfo is always encoded
+// (as an empty field if it is nil), so the encoding stays unambiguous.
+func (fi *FixedInfo) Bytes() []byte {
+	fields := [][]byte{fi.AlgorithmID, fi.PartyUInfo, fi.PartyVInfo}
+	if fi.SuppPubInfo != nil || fi.SuppPrivInfo != nil {
+		fields = append(fields, fi.SuppPubInfo)
+	}
+	if fi.SuppPrivInfo != nil {
+		fields = append(fields, fi.SuppPrivInfo)
+	}
+
+	n := 0
+	for _, f := range fields {
+		n += 4 + len(f)
+	}
+	r := make([]byte, 0, n)
+	for _, f := range fields {
+		var l [4]byte
+		binary.BigEndian.PutUint32(l[:], uint32(len(f)))
+		r = append(r, l[:]...)
+		r = append(r, f...)
+	}
+	return r
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type KDFTestSuite struct {
+	suite.Suite
+	z []byte
+}
+
+func (s *KDFTestSuite) SetupTest() {
+	s.z, _ = hex.DecodeString("00112233445566778899aabbccddeeff")
+}
+
+func (s *KDFTestSuite) TestFixedInfo() {
+	info := &FixedInfo{AlgorithmID: []byte("abc"), PartyVInfo: []byte{0xff}}
+	s.Equal([]byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 0, 0, 0, 0, 1, 0xff}, info.Bytes())
+
+	info.SuppPubInfo = []byte{}
+	info.SuppPrivInfo = []byte{1, 2}
+	s.Equal([]byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 0, 0, 0, 0, 1, 0xff,
+		0, 0, 0, 0, 0, 0, 0, 2, 1, 2}, info.Bytes())
+
+	// a missing SuppPubInfo must not be confused with SuppPrivInfo
+	pub := &FixedInfo{AlgorithmID: []byte("abc"), SuppPubInfo: []byte{1, 2}}
+	priv := &FixedInfo{AlgorithmID: []byte("abc"), SuppPrivInfo: []byte{1, 2}}
+	s.NotEqual(pub.Bytes(), priv.Bytes(), "encodings are equal")
+	s.Equal([]byte{0, 0, 0, 3, 'a', 'b', 'c', 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 0, 0, 0, 0, 0, 0, 2, 1, 2}, priv.Bytes())
+}
+
+func (s *KDFTestSuite) TestOneStep() {
+	kdf := &OneStepKDF{Hash: sha256.New}
+	fixedInfo := []byte{0, 0, 0, 3, 'a', 'b', 'c'}
+
+	key, err := kdf.DeriveKey(s.z, fixedInfo, 40)
+	s.NoError(err, "failed to derive key")
+	s.Equal("c131805c9985ee22a0d92b841f1d31c097633f3a9930a3651843702d3bed3968fec3917c64cdffbf",
+		hex.EncodeToString(key))
+
+	short, err := kdf.DeriveKey(s.z, fixedInfo, 7)
+	s.NoError(err, "failed to derive key")
+	s.Equal(key[:7], short, "key is not a prefix")
+
+	_, err = kdf.DeriveKey(s.z, nil, 0)
+	s.Error(err, "empty key length accepted")
+}
+
+func (s *KDFTestSuite) TestOneStepHMAC() {
+	kdf := &OneStepHMACKDF{Hash: sha256.New, Salt: []byte("salt")}
+	info := &FixedInfo{AlgorithmID: []byte("abc")}
+
+	key, err := kdf.DeriveKey(s.z, info.Bytes(), 40)
+	s.NoError(err, "failed to derive key")
+	s.Equal("8fc18978da55d2539426a7c6dc11698176c2f49700c1aafdf8b33d3f2ad216f12afa8b1e1c3b8850",
+		hex.EncodeToString(key))
+
+	_, err = kdf.DeriveKey(s.z, nil, -1)
+	s.Error(err, "negative key length accepted")
+}
+
+func (s *KDFTestSuite) TestTwoStep() {
+	kdf := &TwoStepKDF{Hash: sha256.New}
+	info := &FixedInfo{AlgorithmID: []byte("abc")}
+
+	key, err := kdf.DeriveKey(s.z, info.Bytes(), 40)
+	s.NoError(err, "failed to derive key")
+	s.Equal("06eba585782ad1869c30bc9fdb534db0a747b3ca7e21f89f1bdec6700bc954b470e9b04114368778",
+		hex.EncodeToString(key))
+
+	zeroSalt := &TwoStepKDF{Hash: sha256.New, Salt: make([]byte, sha256.BlockSize)}
+	key2, err := zeroSalt.DeriveKey(s.z, info.Bytes(), 40)
+	s.NoError(err, "failed to derive key")
+	s.Equal(key, key2, "default salt is not a string of zeros")
+
+	_, err = kdf.DeriveKey(s.z, nil, 0)
+	s.Error(err, "empty key length accepted")
+}
+
+func TestKDF(t *testing.T) {
+	suite.Run(t, new(KDFTestSuite))
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// PublicKey represents a public key on an elliptic curve.
+type PublicKey struct {
+	Curve elliptic.Curve
+	X, Y  *big.Int
+}
+
+// PrivateKey represents a private key on an elliptic curve. D is the private
+// scalar encoded as a big-endian byte slice.
+type PrivateKey struct {
+	Curve elliptic.Curve
+	D     []byte
+}
+
+// KeyPair bundles a private key with its corresponding public key. The
+// functions of this package do not check that Public equals D * G, so key
+// pairs should be created with NewKeyPair or GenerateKeyPair.
+type KeyPair struct {
+	Private *PrivateKey
+	Public  *PublicKey
+}
+
+// NewPrivateKey returns a private key for the given scalar. The scalar must
+// be in the range [1, n-1]. The private key holds a copy of d, since the
+// package wipes private keys that are no longer used.
+func NewPrivateKey(curve elliptic.Curve, d []byte) (*PrivateKey, error) {
+	params := curve.Params()
+	if len(d) > (params.N.BitLen()+7)>>3 {
+		return nil, ErrInvalidPrivateKey
+	}
+	v := new(big.Int).SetBytes(d)
+	defer WipeInt(v)
+	if v.Sign() == 0 || v.Cmp(params.N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return &PrivateKey{Curve: curve, D: append([]byte(nil), d...)}, nil
+}
+
+// Public calculates the public key that belongs to the private key.
+func (priv *PrivateKey) Public() *PublicKey {
+	x, y := priv.Curve.ScalarBaseMult(priv.D)
+	return &PublicKey{Curve: priv.Curve, X: x, Y: y}
+}
+
+// NewKeyPair returns the key pair of the given private key. The public key
+// is calculated from the private key, so both always match.
+func NewKeyPair(priv *PrivateKey) *KeyPair {
+	return &KeyPair{Private: priv, Public: priv.Public()}
+}
+
+// GenerateKeyPair returns a new random key pair on the given curve. The
+// private key is generated using the given reader, which must return random
+// data.
+func GenerateKeyPair(curve elliptic.Curve, rand io.Reader) (*KeyPair, error) {
+	d, err := GenerateKey(curve.Params(), rand)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyPair(&PrivateKey{Curve: curve, D: d}), nil
+}
+
+// checkCurves returns an error unless all keys use the same curve.
+func checkCurves(own []*KeyPair, other []*PublicKey) (elliptic.Curve, error) {
+	var curve elliptic.Curve
+	check := func(c elliptic.Curve) error {
+		if c == nil {
+			return fmt.Errorf("%w: missing curve", ErrUnsupportedCurve)
+		}
+		if curve == nil {
+			curve = c
+		} else if c != curve {
+			return errors.New("keys use different curves")
+		}
+		return nil
+	}
+	for _, kp := range own {
+		if kp == nil || kp.Private == nil || kp.Public == nil {
+			return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+		}
+		if err := check(kp.Private.Curve); err != nil {
+			return nil, err
+		}
+		if err := check(kp.Public.Curve); err != nil {
+			return nil, err
+		}
+	}
+	for _, pub := range other {
+		if pub == nil {
+			return nil, fmt.Errorf("%w: missing key", ErrInvalidPublicKey)
+		}
+		if err := check(pub.Curve); err != nil {
+			return nil, err
+		}
+	}
+	return curve, nil
+}
+
+// Agree runs the MQV primitive with typed keys and returns the shared secret
+// Z. The own static and ephemeral key pairs are combined with the other
+// party's static and ephemeral public keys. In the one-pass form, the
+// receiver passes its static key pair as ownEphemeral and the sender passes
+// the receiver's static public key as otherEphemeral. See MQV for details.
+func Agree(ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error) {
+	curve, err := checkCurves([]*KeyPair{ownStatic, ownEphemeral}, []*PublicKey{otherStatic, otherEphemeral})
+	if err != nil {
+		return nil, err
+	}
+	x, y, err := MQV(ownStatic.Private.D, ownEphemeral.Private.D, ownEphemeral.Public.X,
+		otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, curve)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(x)
+	defer WipeInt(y)
+	return NewSharedSecret(x, curve)
+}
+
+// BlindAgree is similar to Agree, but uses the blinded primitive BlindMQV.
+func BlindAgree(ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey, rand io.Reader) (*SharedSecret, error) {
+	curve, err := checkCurves([]*KeyPair{ownStatic, ownEphemeral}, []*PublicKey{otherStatic, otherEphemeral})
+	if err != nil {
+		return nil, err
+	}
+	x, y, err := BlindMQV(ownStatic.Private.D, ownEphemeral.Private.D, ownEphemeral.Public.X,
+		otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, curve, rand)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeInt(x)
+	defer WipeInt(y)
+	return NewSharedSecret(x, curve)
+}
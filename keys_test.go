@@ -0,0 +1,151 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type KeysTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	aliceStatic    *KeyPair
+	aliceEphemeral *KeyPair
+	bobStatic      *KeyPair
+	bobEphemeral   *KeyPair
+}
+
+func (s *KeysTestSuite) SetupTest() {
+	s.aliceStatic = s.generateKeyPair("alice static")
+	s.aliceEphemeral = s.generateKeyPair("alice ephemeral")
+	s.bobStatic = s.generateKeyPair("bob static")
+	s.bobEphemeral = s.generateKeyPair("bob ephemeral")
+}
+
+func (s *KeysTestSuite) generateKeyPair(name string) *KeyPair {
+	kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoErrorf(err, "failed to create key pair %q", name)
+	return kp
+}
+
+func (s *KeysTestSuite) TestPublic() {
+	x, y := s.Curve.ScalarBaseMult(s.aliceStatic.Private.D)
+	s.Equal(x.Text(16), s.aliceStatic.Public.X.Text(16), "x is not equal")
+	s.Equal(y.Text(16), s.aliceStatic.Public.Y.Text(16), "y is not equal")
+	s.True(s.Curve.IsOnCurve(s.aliceStatic.Public.X, s.aliceStatic.Public.Y), "public key not on curve")
+}
+
+func (s *KeysTestSuite) TestNewPrivateKey() {
+	d := append([]byte(nil), s.aliceStatic.Private.D...)
+	priv, err := NewPrivateKey(s.Curve, d)
+	s.Require().NoError(err, "valid private key rejected")
+	WipeBytes(d)
+	s.Equal(s.aliceStatic.Private.D, priv.D, "private key aliases the given slice")
+
+	_, err = NewPrivateKey(s.Curve, []byte{0})
+	s.Error(err, "zero private key accepted")
+
+	_, err = NewPrivateKey(s.Curve, s.Curve.Params().N.Bytes())
+	s.Error(err, "private key n accepted")
+
+	_, err = NewPrivateKey(s.Curve, make([]byte, len(s.Curve.Params().N.Bytes())+1))
+	s.Error(err, "oversized private key accepted")
+}
+
+func (s *KeysTestSuite) TestAgree() {
+	alice, err := Agree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, s.bobEphemeral.Public)
+	s.NoError(err, "failed to run agree for alice")
+
+	bob, err := Agree(s.bobStatic, s.bobEphemeral, s.aliceStatic.Public, s.aliceEphemeral.Public)
+	s.NoError(err, "failed to run agree for bob")
+
+	s.True(alice.Equal(bob), "shared secrets are not equal")
+
+	rawX, _, err := MQV(s.aliceStatic.Private.D, s.aliceEphemeral.Private.D, s.aliceEphemeral.Public.X,
+		s.bobStatic.Public.X, s.bobStatic.Public.Y, s.bobEphemeral.Public.X, s.bobEphemeral.Public.Y, s.Curve)
+	s.NoError(err, "failed to run mqv for alice")
+	s.Equal(rawX.Text(16), new(big.Int).SetBytes(alice.Bytes()).Text(16), "shared secret is not equal to primitive")
+}
+
+func (s *KeysTestSuite) TestBlindAgree() {
+	alice, err := BlindAgree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, s.bobEphemeral.Public, rand.Reader)
+	s.NoError(err, "failed to run blinded agree for alice")
+
+	bob, err := Agree(s.bobStatic, s.bobEphemeral, s.aliceStatic.Public, s.aliceEphemeral.Public)
+	s.NoError(err, "failed to run agree for bob")
+
+	s.True(alice.Equal(bob), "shared secrets are not equal")
+}
+
+func (s *KeysTestSuite) TestOnePass() {
+	alice, err := Agree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, s.bobStatic.Public)
+	s.NoError(err, "failed to run agree for sender")
+
+	bob, err := Agree(s.bobStatic, s.bobStatic, s.aliceStatic.Public, s.aliceEphemeral.Public)
+	s.NoError(err, "failed to run agree for receiver")
+
+	s.True(alice.Equal(bob), "shared secrets are not equal")
+}
+
+func (s *KeysTestSuite) TestCurveMismatch() {
+	other := elliptic.P256()
+	if s.Curve == other {
+		other = elliptic.P384()
+	}
+	kp, err := GenerateKeyPair(other, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+
+	_, err = Agree(s.aliceStatic, s.aliceEphemeral, kp.Public, s.bobEphemeral.Public)
+	s.Error(err, "mixed curves accepted")
+
+	_, err = Agree(s.aliceStatic, s.aliceEphemeral, s.bobStatic.Public, nil)
+	s.Error(err, "missing public key accepted")
+
+	noCurve := &PublicKey{X: s.bobStatic.Public.X, Y: s.bobStatic.Public.Y}
+	_, err = Agree(s.aliceStatic, s.aliceEphemeral, noCurve, s.bobEphemeral.Public)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "public key without curve accepted: %v", err)
+
+	noCurvePriv := &KeyPair{Private: &PrivateKey{D: s.aliceStatic.Private.D}, Public: noCurve}
+	_, err = Agree(noCurvePriv, s.aliceEphemeral, s.bobStatic.Public, s.bobEphemeral.Public)
+	s.True(errors.Is(err, ErrUnsupportedCurve), "key pair without curve accepted: %v", err)
+}
+
+func TestKeysP224(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: elliptic.P224()})
+}
+
+func TestKeysP256(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: elliptic.P256()})
+}
+
+func TestKeysP384(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: elliptic.P384()})
+}
+
+func TestKeysP521(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: elliptic.P521()})
+}
+
+func TestKeysBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: BrainpoolP256r1()})
+}
+
+func TestKeysBrainpoolP384r1(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: BrainpoolP384r1()})
+}
+
+func TestKeysBrainpoolP512r1(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: BrainpoolP512r1()})
+}
+
+func TestKeysSecp256k1(t *testing.T) {
+	suite.Run(t, &KeysTestSuite{Curve: Secp256k1()})
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"golang.org/x/crypto/sha3"
+)
+
+// kmac calculates KMAC128 (security = 128) or KMAC256 (security = 256) as
+// specified by section 4 of SP 800-185 and returns length bytes of output.
+// It panics for any other security strength, which has to be checked by the
+// caller.
+func kmac(security int, key, data, customization []byte, length int) []byte {
+	var h sha3.ShakeHash
+	var rate int
+	switch security {
+	case 128:
+		h = sha3.NewCShake128([]byte("KMAC"), customization)
+		rate = 168
+	case 256:
+		h = sha3.NewCShake256([]byte("KMAC"), customization)
+		rate = 136
+	default:
+		panic("invalid kmac security strength")
+	}
+
+	// newX = bytepad(encode_string(K), rate) || X || right_encode(L)
+	k := leftEncode(uint64(len(key)) * 8)
+	h.Write(leftEncode(uint64(rate)))
+	h.Write(k)
+	h.Write(key)
+	if pad := (len(leftEncode(uint64(rate))) + len(k) + len(key)) % rate; pad != 0 {
+		h.Write(make([]byte, rate-pad))
+	}
+	h.Write(data)
+	h.Write(rightEncode(uint64(length) * 8))
+
+	out := make([]byte, length)
+	h.Read(out)
+	return out
+}
+
+// leftEncode encodes x as a byte string that can be parsed unambiguously
+// from the beginning.
+func leftEncode(x uint64) []byte {
+	b := encodeUint(x)
+	return append([]byte{byte(len(b))}, b...)
+}
+
+// rightEncode encodes x as a byte string that can be parsed unambiguously
+// from the end.
+func rightEncode(x uint64) []byte {
+	b := encodeUint(x)
+	return append(b, byte(len(b)))
+}
+
+// encodeUint returns the shortest big-endian encoding of x with at least one
+// byte.
+func encodeUint(x uint64) []byte {
+	var b []byte
+	for x > 0 || len(b) == 0 {
+		b = append([]byte{byte(x)}, b...)
+		x >>= 8
+	}
+	return b
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"math/bits"
+)
+
+// MontContext contains the precomputed values for Montgomery arithmetic
+// modulo an odd modulus n. With R = 2^(W*len(n)), where W is the word size,
+// an integer x is represented by x*R mod n in the Montgomery domain. All
+// operations are performed in constant time.
+type MontContext struct {
+	n    SubtleInt
+	nInv uint      // n' = -n^-1 mod 2^W
+	r    SubtleInt // R mod n
+	r2   SubtleInt // R^2 mod n
+}
+
+// NewMontContext returns the Montgomery context for the odd modulus n > 1.
+func NewMontContext(n SubtleInt) *MontContext {
+	if len(n) == 0 || n[0]&1 == 0 {
+		panic("modulus must be odd")
+	}
+	if n.Big().Cmp(one) <= 0 {
+		panic("modulus must be greater than one")
+	}
+
+	// Newton's iteration doubles the number of correct bits in each step.
+	inv := uint(1)
+	for i := 0; i < bits.Len(bits.UintSize); i++ {
+		inv *= 2 - n[0]*inv
+	}
+
+	r := make(SubtleInt, len(n)+1)
+	r[len(n)] = 1
+	r2 := make(SubtleInt, 2*len(n)+1)
+	r2[2*len(n)] = 1
+
+	m := &MontContext{
+		n:    append(SubtleInt(nil), n...),
+		nInv: -inv,
+		r:    make(SubtleInt, len(n)),
+		r2:   make(SubtleInt, len(n)),
+	}
+	m.r.Mod(r, n)
+	m.r2.Mod(r2, n)
+	return m
+}
+
+// Size returns the number of words of the integers used by this context.
+func (m *MontContext) Size() int {
+	return len(m.n)
+}
+
+// Modulus returns the modulus n.
+func (m *MontContext) Modulus() SubtleInt {
+	return append(SubtleInt(nil), m.n...)
+}
+
+// One sets z to 1 in the Montgomery domain, which is R mod n.
+func (m *MontContext) One(z SubtleInt) {
+	copy(z, m.r)
+}
+
+// ToMont sets z to x*R mod n, which converts x < n to the Montgomery domain.
+func (m *MontContext) ToMont(z, x SubtleInt) {
+	m.MontMul(z, x, m.r2)
+}
+
+// FromMont sets z to x*R^-1 mod n, which converts x from the Montgomery
+// domain back to the normal representation.
+func (m *MontContext) FromMont(z, x SubtleInt) {
+	one := make(SubtleInt, len(m.n))
+	one[0] = 1
+	m.MontMul(z, x, one)
+}
+
+// MontMul sets z to x*y*R^-1 mod n. Both x and y must be less than n. This
+// function implements the coarsely integrated operand scanning (CIOS)
+// method.
+func (m *MontContext) MontMul(z, x, y SubtleInt) {
+	k := len(m.n)
+	if len(x) != k || len(y) != k || len(z) != k {
+		panic("size mismatch")
+	}
+	t := make(SubtleInt, k+2)
+	defer t.SetZero()
+
+	for i := 0; i < k; i++ {
+		// t = t + x[i]*y
+		var c, c1, c2 uint
+		for j := 0; j < k; j++ {
+			hi, lo := bits.Mul(x[i], y[j])
+			lo, c1 = addW(lo, t[j], 0)
+			lo, c2 = addW(lo, c, 0)
+			t[j] = lo
+			c = hi + c1 + c2
+		}
+		t[k], c1 = addW(t[k], c, 0)
+		t[k+1] += c1
+
+		// t = (t + u*n) / 2^W, where u is chosen such that the lowest word
+		// of the sum is zero
+		u := t[0] * m.nInv
+		hi, lo := bits.Mul(u, m.n[0])
+		_, c1 = addW(lo, t[0], 0)
+		c = hi + c1
+		for j := 1; j < k; j++ {
+			hi, lo = bits.Mul(u, m.n[j])
+			lo, c1 = addW(lo, t[j], 0)
+			lo, c2 = addW(lo, c, 0)
+			t[j-1] = lo
+			c = hi + c1 + c2
+		}
+		t[k-1], c1 = addW(t[k], c, 0)
+		t[k] = t[k+1] + c1
+		t[k+1] = 0
+	}
+
+	// t < 2n, so at most one subtraction is required
+	tmp := make(SubtleInt, k)
+	defer tmp.SetZero()
+	borrow := tmp.Sub(t[:k], m.n)
+	z.Select(t[k]|(borrow^1), tmp, t[:k])
+}
+
+// MontSquare sets z to x*x*R^-1 mod n.
+func (m *MontContext) MontSquare(z, x SubtleInt) {
+	m.MontMul(z, x, x)
+}
+
+// Exp sets z to x^e mod n, where x < n and z are in the normal
+// representation. The exponent can have any length. A fixed window of 4
+// bits is used and the precomputed powers of x are selected in constant
+// time.
+func (m *MontContext) Exp(z, x, e SubtleInt) {
+	const window = 4
+	k := len(m.n)
+
+	var table [1 << window]SubtleInt
+	table[0] = make(SubtleInt, k)
+	m.One(table[0])
+	table[1] = make(SubtleInt, k)
+	m.ToMont(table[1], x)
+	for i := 2; i < len(table); i++ {
+		table[i] = make(SubtleInt, k)
+		m.MontMul(table[i], table[i-1], table[1])
+	}
+	defer func() {
+		for i := range table {
+			table[i].SetZero()
+		}
+	}()
+
+	acc := make(SubtleInt, k)
+	defer acc.SetZero()
+	m.One(acc)
+	t := make(SubtleInt, k)
+	defer t.SetZero()
+
+	for i := len(e) - 1; i >= 0; i-- {
+		for j := bits.UintSize - window; j >= 0; j -= window {
+			for s := 0; s < window; s++ {
+				m.MontSquare(acc, acc)
+			}
+			idx := (e[i] >> uint(j)) & (1<<window - 1)
+			for v := range table {
+				t.Select(equalW(uint(v), idx), table[v], t)
+			}
+			m.MontMul(acc, acc, t)
+		}
+	}
+	m.FromMont(z, acc)
+}
+
+// equalW returns 1 if a == b and 0 otherwise.
+func equalW(a, b uint) uint {
+	return lessEqW(a, b) & lessEqW(b, a)
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"math/bits"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MontTestSuite struct {
+	testValues2 []SubtleInt // list of interesting values of size 2 (cross product)
+	moduli      []SubtleInt // list of odd moduli of size 2
+	suite.Suite
+}
+
+func (t *MontTestSuite) SetupSuite() {
+	const maxUint = ^uint(0)
+	values := []uint{0, 1, 2, 3, maxUint, maxUint - 1, maxUint - 2, maxUint >> 1, maxUint>>1 + 1, maxUint>>1 - 1}
+	for _, a := range values {
+		for _, b := range values {
+			x := SubtleInt{a, b}
+			t.testValues2 = append(t.testValues2, x)
+			if a&1 == 1 && x.Big().Cmp(one) > 0 {
+				t.moduli = append(t.moduli, x)
+			}
+		}
+	}
+}
+
+// reduce returns the values of testValues2 reduced modulo n.
+func (t *MontTestSuite) reduce(n SubtleInt) []SubtleInt {
+	r := make([]SubtleInt, len(t.testValues2))
+	for i, x := range t.testValues2 {
+		r[i] = make(SubtleInt, len(n))
+		r[i].Mod(x, n)
+	}
+	return r
+}
+
+// bigR returns R = 2^(W*size).
+func bigR(size int) *big.Int {
+	return new(big.Int).Lsh(one, uint(size*bits.UintSize))
+}
+
+func (t *MontTestSuite) TestNewMontContext() {
+	t.Panics(func() { NewMontContext(SubtleInt{4, 1}) }, "must not accept even moduli")
+	t.Panics(func() { NewMontContext(SubtleInt{1, 0}) }, "must not accept modulus one")
+	t.Panics(func() { NewMontContext(SubtleInt{}) }, "must not accept empty moduli")
+
+	for _, n := range t.moduli {
+		m := NewMontContext(n)
+		bigN := n.Big()
+		wantR := new(big.Int).Mod(bigR(len(n)), bigN)
+		t.Equalf(fmtHex(wantR), fmtHex(m.r.Big()), "r for %v", n)
+		wantR2 := new(big.Int).Mul(wantR, wantR)
+		wantR2.Mod(wantR2, bigN)
+		t.Equalf(fmtHex(wantR2), fmtHex(m.r2.Big()), "r2 for %v", n)
+		t.Equalf(^uint(0), m.nInv*n[0], "nInv for %v", n)
+		t.Equal(n, m.Modulus(), "modulus")
+		t.Equal(len(n), m.Size(), "size")
+	}
+}
+
+func (t *MontTestSuite) TestConvert() {
+	for _, n := range t.moduli {
+		m := NewMontContext(n)
+		bigN := n.Big()
+		for _, x := range t.reduce(n) {
+			xm := make(SubtleInt, len(n))
+			m.ToMont(xm, x)
+			want := new(big.Int).Mul(x.Big(), bigR(len(n)))
+			want.Mod(want, bigN)
+			t.Equalf(fmtHex(want), fmtHex(xm.Big()), "toMont(%v) mod %v", x, n)
+
+			r := make(SubtleInt, len(n))
+			m.FromMont(r, xm)
+			t.Equalf(x, r, "fromMont(toMont(%v)) mod %v", x, n)
+		}
+	}
+}
+
+func (t *MontTestSuite) TestMontMul() {
+	for _, n := range t.moduli {
+		m := NewMontContext(n)
+		bigN := n.Big()
+		rInv := new(big.Int).ModInverse(bigR(len(n)), bigN)
+		values := t.reduce(n)
+		for _, a := range values {
+			for _, b := range values {
+				r := make(SubtleInt, len(n))
+				m.MontMul(r, a, b)
+
+				want := new(big.Int).Mul(a.Big(), b.Big())
+				want.Mul(want, rInv)
+				want.Mod(want, bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "montMul(%v, %v) mod %v", a, b, n)
+			}
+
+			sq := make(SubtleInt, len(n))
+			m.MontSquare(sq, a)
+			want := new(big.Int).Mul(a.Big(), a.Big())
+			want.Mul(want, rInv)
+			want.Mod(want, bigN)
+			t.Equalf(fmtHex(want), fmtHex(sq.Big()), "montSquare(%v) mod %v", a, n)
+		}
+
+		r := make(SubtleInt, len(n))
+		t.Panics(func() { m.MontMul(r, n[:1], n) }, "must not multiply integers with different lengths")
+		t.Panics(func() { m.MontMul(r[:1], n, n) }, "must not multiply integers with different lengths")
+	}
+}
+
+func (t *MontTestSuite) TestExp() {
+	for _, n := range t.moduli {
+		m := NewMontContext(n)
+		bigN := n.Big()
+		values := t.reduce(n)
+		for _, x := range values[:20] {
+			for _, e := range t.testValues2 {
+				r := make(SubtleInt, len(n))
+				m.Exp(r, x, e)
+
+				want := new(big.Int).Exp(x.Big(), e.Big(), bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "exp(%v, %v) mod %v", x, e, n)
+			}
+		}
+	}
+}
+
+func (t *MontTestSuite) TestRandom() {
+	curves := []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()}
+	for _, curve := range curves {
+		for _, bigN := range []*big.Int{curve.Params().P, curve.Params().N} {
+			size := SubtleIntSize(bigN.BitLen())
+			m := NewMontContext(subtleFromBytes(bigN.Bytes(), size))
+
+			for i := 0; i < 8; i++ {
+				bigA, err := rand.Int(rand.Reader, bigN)
+				t.Require().NoError(err, "failed to generate random number")
+				bigB, err := rand.Int(rand.Reader, bigN)
+				t.Require().NoError(err, "failed to generate random number")
+				a := subtleFromBytes(bigA.Bytes(), size)
+				b := subtleFromBytes(bigB.Bytes(), size)
+
+				am := make(SubtleInt, size)
+				bm := make(SubtleInt, size)
+				r := make(SubtleInt, size)
+				m.ToMont(am, a)
+				m.ToMont(bm, b)
+				m.MontMul(r, am, bm)
+				m.FromMont(r, r)
+				want := new(big.Int).Mul(bigA, bigB)
+				want.Mod(want, bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "%s: %v * %v", curve.Params().Name, bigA, bigB)
+
+				m.Exp(r, a, b)
+				want.Exp(bigA, bigB, bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "%s: %v ^ %v", curve.Params().Name, bigA, bigB)
+			}
+		}
+	}
+}
+
+func TestMont(t *testing.T) {
+	suite.Run(t, new(MontTestSuite))
+}
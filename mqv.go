@@ -8,38 +8,20 @@ import (
 	"fmt"
 	"io"
 	"math/big"
-
-	"github.com/pkg/errors"
 )
 
 var (
 	one = big.NewInt(1)
 )
 
-// cofactor returns the cofactor (number of points on the elliptic curve vs.
-// number of elements in the cyclic group) of the elliptic curve.
-func cofactor(curve elliptic.Curve) (*big.Int, error) {
-	switch curve {
-	case elliptic.P224():
-		return one, nil
-	case elliptic.P256():
-		return one, nil
-	case elliptic.P384():
-		return one, nil
-	case elliptic.P521():
-		return one, nil
-	default:
-		return nil, fmt.Errorf("failed to determine cofactor of curve %q", curve.Params().Name)
-	}
-}
-
-// avf is the associative value function. It is used by the ECC MQV family of
+// avf is the associative value function. It is used by the MQV family of
 // key-agreement schemes to compute an integer that is associated with an
-// elliptic curve point. This function implements the recommendation given
-// by section 5.7.2.2 in SP 800-56A Rev. 3.
-func avf(x *big.Int, params *elliptic.CurveParams) *big.Int {
-	f := uint(params.N.BitLen())        // f = ceil(log2(n))
-	b := new(big.Int).Lsh(one, (f+1)/2) // b = 2^ceil(f/2)
+// elliptic curve point (x is its x-coordinate) or a finite field element (x
+// is the element itself). bits is ceil(f/2) for the order n of the group,
+// see avfBits. This function implements the recommendation given by sections
+// 5.7.2.1 and 5.7.2.2 in SP 800-56A Rev. 3.
+func avf(x *big.Int, bits int) *big.Int {
+	b := new(big.Int).Lsh(one, uint(bits)) // b = 2^ceil(f/2)
 	defer WipeInt(b)
 
 	// v = (x mod b) + b = ((b - 1) & x) + b
@@ -50,30 +32,96 @@ func avf(x *big.Int, params *elliptic.CurveParams) *big.Int {
 	return v
 }
 
+// avfBits returns ceil(f/2) with f = ceil(log2(n)), which is the number of
+// bits of x that are kept by avf for the order n.
+func avfBits(n *big.Int) int {
+	return (n.BitLen() + 1) / 2
+}
+
 // mqvSig calculates h * (ownEphemeralPriv + avf(ownEphemeralPublic) * ownStaticPriv)) mod n
-func mqvSig(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX *big.Int, curve elliptic.Curve, h *big.Int) []byte {
-	params := curve.Params()
-	ownStaticPrivInt := new(big.Int).SetBytes(ownStaticPriv)
-	defer WipeInt(ownStaticPrivInt)
-	ownEphemeralPrivInt := new(big.Int).SetBytes(ownEphemeralPriv)
-	defer WipeInt(ownEphemeralPrivInt)
-	implSig := avf(ownEphemeralX, params)
-	defer WipeInt(implSig)
-	implSig = implSig.Mul(implSig, ownStaticPrivInt)
-	implSig = implSig.Add(implSig, ownEphemeralPrivInt)
-	implSig = implSig.Mod(implSig, params.N)
-	implSig = implSig.Mul(implSig, h)
-	return implSig.Bytes()
+// in constant time using SubtleInt, where n is the order of the group and
+// bits the parameter of avf. The result is a big-endian byte slice of the
+// byte length of n * h.
+func mqvSig(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX *big.Int, bits int, order, h *big.Int) []byte {
+	avfOwn := avf(ownEphemeralX, bits)
+	defer WipeInt(avfOwn)
+	return implicitSig(ownStaticPriv, ownEphemeralPriv, avfOwn, order, h)
+}
+
+// implicitSig calculates the implicit signature
+// h * (ownEphemeralPriv + factor * ownStaticPriv)) mod n, see mqvSig. The
+// factor is avf(ownEphemeralPublic) for MQV and a hash value for HMQV.
+func implicitSig(ownStaticPriv, ownEphemeralPriv []byte, factor, order, h *big.Int) []byte {
+	n := subtleFromBytes(order.Bytes(), SubtleIntSize(order.BitLen()))
+
+	ownStaticPrivInt := subtleModN(ownStaticPriv, n)
+	defer ownStaticPrivInt.SetZero()
+	ownEphemeralPrivInt := subtleModN(ownEphemeralPriv, n)
+	defer ownEphemeralPrivInt.SetZero()
+	factorInt := subtleModN(factor.Bytes(), n)
+	defer factorInt.SetZero()
+
+	implSig := make(SubtleInt, len(n))
+	defer implSig.SetZero()
+	implSig.MulMod(factorInt, ownStaticPrivInt, n)
+	implSig.AddMod(implSig, ownEphemeralPrivInt, n)
+
+	hBytes := h.Bytes()
+	hInt := subtleFromBytes(hBytes, SubtleIntSize(8*len(hBytes)))
+	r := make(SubtleInt, len(implSig)+len(hInt))
+	defer r.SetZero()
+	r.Mul(implSig, hInt)
+
+	numBytes := (new(big.Int).Mul(order, h).BitLen() + 7) >> 3
+	rBytes := r.Bytes()
+	defer WipeBytes(rBytes)
+	return append([]byte(nil), rBytes[len(rBytes)-numBytes:]...)
+}
+
+// subtleFromBytes returns a SubtleInt with size words and the value of the
+// big-endian byte slice buf.
+func subtleFromBytes(buf []byte, size int) SubtleInt {
+	z := make(SubtleInt, size)
+	z.setValue(buf)
+	return z
+}
+
+// subtleModN returns the value of the big-endian byte slice buf reduced
+// modulo n in constant time.
+func subtleModN(buf []byte, n SubtleInt) SubtleInt {
+	x := subtleFromBytes(buf, SubtleIntSize(8*len(buf)))
+	defer x.SetZero()
+	z := make(SubtleInt, len(n))
+	z.Mod(x, n)
+	return z
+}
+
+// checkPeerRange checks the coordinates of the static and ephemeral public
+// keys of the other party with checkRange.
+func checkPeerRange(curve elliptic.Curve, staticX, staticY, ephemeralX, ephemeralY *big.Int) error {
+	if err := checkRange(curve, staticX, staticY); err != nil {
+		return fmt.Errorf("invalid static key: %w", err)
+	}
+	if err := checkRange(curve, ephemeralX, ephemeralY); err != nil {
+		return fmt.Errorf("invalid ephemeral key: %w", err)
+	}
+	return nil
 }
 
 // mqvBase calculates otherEphemeralPublic + avf(otherEphemeralPublic) * otherStaticPublic.
-func mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, curve elliptic.Curve) (*big.Int, *big.Int) {
-	avfOther := avf(otherEphemeralX, curve.Params())
+func mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, ci *CurveInfo) (*big.Int, *big.Int) {
+	avfOther := avf(otherEphemeralX, ci.AvfBits())
 	defer WipeInt(avfOther)
-	avfOtherBytes := avfOther.Bytes()
-	defer WipeBytes(avfOtherBytes)
+	return implicitBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, avfOther, ci.Curve)
+}
+
+// implicitBase calculates otherEphemeralPublic + factor * otherStaticPublic,
+// see mqvBase.
+func implicitBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, factor *big.Int, curve elliptic.Curve) (*big.Int, *big.Int) {
+	factorBytes := factor.Bytes()
+	defer WipeBytes(factorBytes)
 
-	ax, ay := curve.ScalarMult(otherStaticX, otherStaticY, avfOtherBytes)
+	ax, ay := curve.ScalarMult(otherStaticX, otherStaticY, factorBytes)
 	defer WipeInt(ax)
 	defer WipeInt(ay)
 
@@ -86,24 +134,33 @@ func mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.I
 // other party's public keys. In the full form, each party has a static
 // and a ephemeral key. In the one-pass form the other party only has
 // a static key which is used twice with this primitive.
-// h is the cofactor of the elliptic curve.
+// h is the cofactor of the elliptic curve, which is taken from the registry
+// (see LookupCurve). The arithmetic of the registered curve is used.
+// The public keys of the other party are not validated by this primitive,
+// see ValidatePublicKeyFull and ValidatePublicKeyPartial. Only coordinates
+// outside of the range [0, p-1] are rejected with ErrInvalidPublicKey.
+// ErrIdentity is returned if the shared secret is the point at infinity.
 // See section 5.7.2.3 of SP 800-56A Rev. 3 for more details.
 func MQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, curve elliptic.Curve) (*big.Int, *big.Int, error) {
-	h, err := cofactor(curve)
+	info, err := LookupCurve(curve)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to get cofactor")
+		return nil, nil, fmt.Errorf("failed to look up curve: %w", err)
+	}
+	curve, h := info.Curve, info.Cofactor
+	if err := checkPeerRange(curve, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY); err != nil {
+		return nil, nil, err
 	}
 
-	s := mqvSig(ownStaticPriv, ownEphemeralPriv, ownEphemeralX, curve, h)
+	s := mqvSig(ownStaticPriv, ownEphemeralPriv, ownEphemeralX, info.AvfBits(), curve.Params().N, h)
 	defer WipeBytes(s)
 
-	bx, by := mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, curve)
+	bx, by := mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, info)
 	defer WipeInt(bx)
 	defer WipeInt(by)
 
-	x, y := curve.ScalarMult(bx, by, s)
-	if x.Sign() == 0 {
-		return nil, nil, fmt.Errorf("failed to generate shared secret")
+	x, y := scalarMult(curve, bx, by, s)
+	if isInfinity(x, y) {
+		return nil, nil, ErrIdentity
 	}
 	return x, y, nil
 }
@@ -112,10 +169,12 @@ func MQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, ot
 // to prevent side channel attacks.
 //
 // Usually Z is calculated with mqvSig(ownStaticPriv, ownEphemeralPriv) * mqvBase()
-// (see mqvSimple), but this might leak information about the private keys on
-// various side channels (e.g. timing or power consumption) since neither
-// the elliptic curve implementation nor the big number implementation is
-// constant time.
+// (see MQV). While mqvSig itself is calculated in constant time, this might
+// still leak information about the private keys on various side channels
+// (e.g. timing or power consumption). The scalar multiplication is constant
+// time for the standard library curves and for curves defined by
+// elliptic.CurveParams, but other implementations of elliptic.Curve might
+// not be.
 // Therefore we blind each key by a random number 0 <= r < n. Assuming r is
 // completely random, then (originalPrivKey + r) mod n has also full entropy,
 // as well as -r mod n. We do this for both private keys. The blinding process
@@ -124,45 +183,100 @@ func MQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, ot
 // Z is now calculated by mqvSig(ownStaticPriv + r1, ownEphemeralPriv + r2) *
 // mqvBase() + mqvSig(-r1, -r2) * mqvBase(), which are basically two MQV
 // primitives with random keys instead of one using the original key.
-func BlindMQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, curve elliptic.Curve, rand io.Reader) (*big.Int, *big.Int, error) {
-	params := curve.Params()
-	h, err := cofactor(curve)
+//
+// Additional countermeasures can be selected by modes. With
+// BlindMultiplicative, the implicit signature s = mqvSig(ownStaticPriv,
+// ownEphemeralPriv) is calculated in constant time and blinded by a random
+// factor r instead, i.e. Z = (h * r) * ((s * r^-1 mod n) * mqvBase()). With
+// BlindPoint, the projective representation of mqvBase() is randomized
+// before each scalar multiplication.
+// Like MQV, it returns ErrInvalidPublicKey if a coordinate of the other
+// party's public keys is out of range and ErrIdentity if Z is the point at
+// infinity.
+func BlindMQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, curve elliptic.Curve, rand io.Reader, modes ...BlindMode) (*big.Int, *big.Int, error) {
+	mode := blindMode(modes)
+	info, err := LookupCurve(curve)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to get cofactor")
+		return nil, nil, fmt.Errorf("failed to look up curve: %w", err)
 	}
+	curve, h := info.Curve, info.Cofactor
+	if err := checkPeerRange(curve, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY); err != nil {
+		return nil, nil, err
+	}
+
+	bx, by := mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, info)
+	defer WipeInt(bx)
+	defer WipeInt(by)
+
+	avfOwn := avf(ownEphemeralX, info.AvfBits())
+	defer WipeInt(avfOwn)
+	return blindImplicit(ownStaticPriv, ownEphemeralPriv, avfOwn, bx, by, curve, h, rand, mode)
+}
 
+// blindImplicit calculates Z = implicitSig(ownStaticPriv, ownEphemeralPriv,
+// factor) * (bx, by) with the countermeasures selected by mode, see BlindMQV.
+// ErrIdentity is returned if Z is the point at infinity.
+func blindImplicit(ownStaticPriv, ownEphemeralPriv []byte, factor, bx, by *big.Int, curve elliptic.Curve, h *big.Int, rand io.Reader, mode BlindMode) (*big.Int, *big.Int, error) {
+	var x, y *big.Int
+	var err error
+	if mode&BlindMultiplicative != 0 {
+		s := implicitSig(ownStaticPriv, ownEphemeralPriv, factor, curve.Params().N, one)
+		defer WipeBytes(s)
+
+		x, y, err = scalarMultBlindMul(curve, bx, by, s, h, rand, mode)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		x, y, err = blindMQVAdditive(ownStaticPriv, ownEphemeralPriv, factor, bx, by, curve, h, rand, mode)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if isInfinity(x, y) {
+		return nil, nil, ErrIdentity
+	}
+	return x, y, nil
+}
+
+// blindMQVAdditive calculates Z = s1 * (bx, by) + s2 * (bx, by) with the
+// additively blinded implicit signatures s1 and s2, see BlindMQV.
+func blindMQVAdditive(ownStaticPriv, ownEphemeralPriv []byte, factor, bx, by *big.Int, curve elliptic.Curve, h *big.Int, rand io.Reader, mode BlindMode) (*big.Int, *big.Int, error) {
+	params := curve.Params()
 	ownStaticPrivNew, ownStaticPrivRev, err := BlindKey(ownStaticPriv, params, rand)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to blind static key")
+		return nil, nil, fmt.Errorf("failed to blind static key: %w", err)
 	}
 	defer WipeBytes(ownStaticPrivNew)
 	defer WipeBytes(ownStaticPrivRev)
 
 	ownEphemeralPrivNew, ownEphemeralPrivRev, err := BlindKey(ownEphemeralPriv, params, rand)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "failed to blind ephemeral key")
+		return nil, nil, fmt.Errorf("failed to blind ephemeral key: %w", err)
 	}
 	defer WipeBytes(ownEphemeralPrivNew)
 	defer WipeBytes(ownEphemeralPrivRev)
 
-	bx, by := mqvBase(otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY, curve)
-	defer WipeInt(bx)
-	defer WipeInt(by)
-
-	s1 := mqvSig(ownStaticPrivNew, ownEphemeralPrivNew, ownEphemeralX, curve, h)
+	s1 := implicitSig(ownStaticPrivNew, ownEphemeralPrivNew, factor, params.N, h)
 	defer WipeBytes(s1)
 
-	x1, y1 := curve.ScalarMult(bx, by, s1)
+	x1, y1, err := scalarMultMode(curve, bx, by, s1, rand, mode)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer WipeInt(x1)
 	defer WipeInt(y1)
 
-	s2 := mqvSig(ownStaticPrivRev, ownEphemeralPrivRev, ownEphemeralX, curve, h)
+	s2 := implicitSig(ownStaticPrivRev, ownEphemeralPrivRev, factor, params.N, h)
 	defer WipeBytes(s2)
 
-	x2, y2 := curve.ScalarMult(bx, by, s2)
+	x2, y2, err := scalarMultMode(curve, bx, by, s2, rand, mode)
+	if err != nil {
+		return nil, nil, err
+	}
 	defer WipeInt(x2)
 	defer WipeInt(y2)
 
-	x, y := curve.Add(x1, y1, x2, y2)
+	x, y := addPoints(curve, x1, y1, x2, y2)
 	return x, y, nil
 }
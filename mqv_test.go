@@ -69,6 +69,32 @@ func (s *MQVTestSuite) TestBlinded() {
 	s.EqualBig(aliceY, aliceBlindY, "y is not equal")
 }
 
+func (s *MQVTestSuite) TestBlindedShortKey() {
+	// private keys with leading zero bytes, both with full length and
+	// trimmed like big.Int.Bytes returns them
+	staticPriv := append([]byte(nil), s.aliceStaticPriv...)
+	staticPriv[0], staticPriv[1] = 0, 0
+	ephemeralPriv := append([]byte(nil), s.aliceEphemeralPriv...)
+	ephemeralPriv[0] = 0
+	ephemeralX, _ := s.Curve.ScalarBaseMult(ephemeralPriv)
+
+	for name, keys := range map[string][2][]byte{
+		"padded":  {staticPriv, ephemeralPriv},
+		"trimmed": {staticPriv[2:], ephemeralPriv[1:]},
+	} {
+		aliceX, aliceY, err := MQV(keys[0], keys[1],
+			ephemeralX, s.bobStaticX, s.bobStaticY, s.bobEphemeralX, s.bobEphemeralY, s.Curve)
+		s.Require().NoErrorf(err, "failed to run simple mqv with %s keys", name)
+
+		aliceBlindX, aliceBlindY, err := BlindMQV(keys[0], keys[1],
+			ephemeralX, s.bobStaticX, s.bobStaticY, s.bobEphemeralX, s.bobEphemeralY, s.Curve, rand.Reader)
+		s.Require().NoErrorf(err, "failed to run blinded mqv with %s keys", name)
+
+		s.EqualBig(aliceX, aliceBlindX, "x is not equal for "+name+" keys")
+		s.EqualBig(aliceY, aliceBlindY, "y is not equal for "+name+" keys")
+	}
+}
+
 func (s *MQVTestSuite) EqualBig(expected, actual *big.Int, msg string) {
 	s.T().Helper()
 	s.Equal(expected.Text(16), actual.Text(16), msg)
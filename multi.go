@@ -0,0 +1,219 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// multiVersion is the version of the envelope produced by SealMulti.
+const multiVersion = 1
+
+// SealMulti encrypts and authenticates the plaintext for several recipients.
+// The plaintext is encrypted once with a random content-encryption key,
+// which is wrapped for every recipient with the one-pass MQV key agreement
+// C(1e, 2s) between the static key of the sender and the static key of the
+// recipient, see OnePassInitiate. The wrapping key is derived like for Seal.
+// All keys must use the same curve, which must be registered with an OID.
+//
+// The envelope has the following format:
+//
+//	version (1 byte) || cipher (1 byte) || curve OID (DER) ||
+//	number of recipients (2 bytes) || stanza ... || ciphertext
+//
+// Every stanza consists of the KeyID of the recipient, the ephemeral public
+// key (uncompressed point) and the wrapped content-encryption key.
+//
+// Note that the recipients share the content-encryption key. Every recipient
+// is able to create a ciphertext for the same stanzas, i.e. the sender is
+// only authenticated to the recipients as the originator of the
+// content-encryption key, not of the plaintext. Use Seal for every recipient
+// if this is not acceptable.
+func SealMulti(senderStatic *KeyPair, recipients []*PublicKey, plaintext, aad []byte, config *SealConfig) ([]byte, error) {
+	if senderStatic == nil || senderStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	if len(recipients) == 0 || len(recipients) > math.MaxUint16 {
+		return nil, fmt.Errorf("invalid number of recipients %d", len(recipients))
+	}
+	c := config.cipher()
+	keySize, err := c.keySize()
+	if err != nil {
+		return nil, err
+	}
+	oid, err := marshalCurveOID(senderStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	cek := make([]byte, keySize)
+	defer WipeBytes(cek)
+	if _, err := io.ReadFull(config.rand(), cek); err != nil {
+		return nil, &RandomError{Err: err}
+	}
+
+	header := []byte{multiVersion, byte(c)}
+	header = append(header, oid...)
+	prefixLen := len(header)
+	header = append(header, 0, 0)
+	binary.BigEndian.PutUint16(header[len(header)-2:], uint16(len(recipients)))
+
+	keyIDs := make([][]byte, 0, len(recipients))
+	for i, recipient := range recipients {
+		keyID, err := KeyID(recipient)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %d: %w", i, err)
+		}
+		for _, id := range keyIDs {
+			if bytes.Equal(id, keyID) {
+				return nil, fmt.Errorf("duplicate recipient %d", i)
+			}
+		}
+		keyIDs = append(keyIDs, keyID)
+
+		stanza, err := wrapKey(senderStatic, recipient, c, header[:prefixLen], keyID, cek, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap key for recipient %d: %w", i, err)
+		}
+		header = append(header, stanza...)
+	}
+
+	aead, err := c.aead(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	// the content-encryption key is only used once
+	nonce := make([]byte, aeadNonceSize)
+	return aead.Seal(header, nonce, plaintext, sealAD(header, aad)), nil
+}
+
+// wrapKey returns the stanza of the recipient with the wrapped
+// content-encryption key. prefix contains the version, the cipher and the
+// curve OID of the envelope.
+func wrapKey(senderStatic *KeyPair, recipient *PublicKey, c Cipher, prefix, keyID, cek []byte, config *SealConfig) ([]byte, error) {
+	ephemeral, z, err := OnePassInitiate(senderStatic, recipient, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	point, err := MarshalPublicKey(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	stanza := make([]byte, 0, len(keyID)+len(point)+len(cek)+aeadOverhead)
+	stanza = append(stanza, keyID...)
+	stanza = append(stanza, point...)
+	aead, nonce, err := deriveAEAD(z, c, "mqv-wrap", senderStatic.Public, recipient, wrapInfo(prefix, stanza), aeadNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(stanza, nonce, cek, nil), nil
+}
+
+// wrapInfo returns the data bound to the wrapping key, i.e. the prefix of the
+// envelope, the KeyID of the recipient and the ephemeral public key.
+func wrapInfo(prefix, stanza []byte) []byte {
+	info := make([]byte, 0, len(prefix)+len(stanza))
+	info = append(info, prefix...)
+	return append(info, stanza...)
+}
+
+// OpenMulti decrypts and authenticates an envelope created by SealMulti with
+// the own static key pair of the recipient and the static public key of the
+// sender. The stanza of the recipient is found by the KeyID of its static
+// public key, ErrUnknownKey is returned if the envelope does not contain
+// one. The additional data aad must be the same as for SealMulti. Only the
+// Rand field of the config is used.
+func OpenMulti(recipientStatic *KeyPair, senderStatic *PublicKey, envelope, aad []byte, config *SealConfig) ([]byte, error) {
+	if recipientStatic == nil || recipientStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	if len(envelope) < 2 {
+		return nil, fmt.Errorf("%w: envelope too short", ErrInvalidMessage)
+	}
+	if envelope[0] != multiVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidMessage, envelope[0])
+	}
+	c := Cipher(envelope[1])
+	keySize, err := c.keySize()
+	if err != nil {
+		return nil, err
+	}
+	ci, rest, err := unmarshalCurveOID(envelope[2:], recipientStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+	prefix := envelope[:len(envelope)-len(rest)]
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("%w: envelope too short", ErrInvalidMessage)
+	}
+	count := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	pointLen := 1 + 2*ci.FieldBytes()
+	stanzaLen := KeyIDSize + pointLen + keySize + aeadOverhead
+	if count == 0 || len(rest) < count*stanzaLen {
+		return nil, fmt.Errorf("%w: invalid number of recipients", ErrInvalidMessage)
+	}
+	stanzas, ct := rest[:count*stanzaLen], rest[count*stanzaLen:]
+	header := envelope[:len(envelope)-len(ct)]
+
+	keyID, err := KeyID(recipientStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+	var stanza []byte
+	for i := 0; i < count; i++ {
+		s := stanzas[i*stanzaLen : (i+1)*stanzaLen]
+		if bytes.Equal(s[:KeyIDSize], keyID) {
+			stanza = s
+			break
+		}
+	}
+	if stanza == nil {
+		return nil, fmt.Errorf("%w %x", ErrUnknownKey, keyID)
+	}
+
+	cek, err := unwrapKey(recipientStatic, senderStatic, ci, c, prefix, stanza, config)
+	if err != nil {
+		return nil, err
+	}
+	defer WipeBytes(cek)
+	aead, err := c.aead(cek)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	plaintext, err := aead.Open(nil, make([]byte, aeadNonceSize), ct, sealAD(header, aad))
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// unwrapKey returns the content-encryption key of the stanza.
+func unwrapKey(recipientStatic *KeyPair, senderStatic *PublicKey, ci *CurveInfo, c Cipher, prefix, stanza []byte, config *SealConfig) ([]byte, error) {
+	pointLen := 1 + 2*ci.FieldBytes()
+	ephemeral, err := UnmarshalPublicKey(ci.Curve, stanza[KeyIDSize:KeyIDSize+pointLen])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ephemeral key: %w", err)
+	}
+	z, err := OnePassRespond(recipientStatic, senderStatic, ephemeral, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	aead, nonce, err := deriveAEAD(z, c, "mqv-wrap", senderStatic, recipientStatic.Public, wrapInfo(prefix, stanza[:KeyIDSize+pointLen]), aeadNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := aead.Open(nil, nonce, stanza[KeyIDSize+pointLen:], nil)
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return cek, nil
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type MultiTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	sender     *KeyPair
+	recipients []*KeyPair
+}
+
+func (s *MultiTestSuite) SetupTest() {
+	var err error
+	s.sender, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the sender")
+	s.recipients = make([]*KeyPair, 3)
+	for i := range s.recipients {
+		s.recipients[i], err = GenerateKeyPair(s.Curve, rand.Reader)
+		s.Require().NoErrorf(err, "failed to create key pair of recipient %d", i)
+	}
+}
+
+func (s *MultiTestSuite) publicKeys() []*PublicKey {
+	keys := make([]*PublicKey, len(s.recipients))
+	for i, kp := range s.recipients {
+		keys[i] = kp.Public
+	}
+	return keys
+}
+
+func (s *MultiTestSuite) TestSealOpen() {
+	plaintext := []byte("attack at dawn")
+	aad := []byte("header")
+
+	for _, c := range []Cipher{AES256GCM, ChaCha20Poly1305} {
+		envelope, err := SealMulti(s.sender, s.publicKeys(), plaintext, aad, &SealConfig{Cipher: c})
+		s.Require().NoErrorf(err, "failed to seal with cipher %d", c)
+
+		for i, kp := range s.recipients {
+			opened, err := OpenMulti(kp, s.sender.Public, envelope, aad, nil)
+			s.Require().NoErrorf(err, "failed to open for recipient %d with cipher %d", i, c)
+			s.Equalf(plaintext, opened, "plaintext differs for recipient %d", i)
+		}
+
+		_, err = OpenMulti(s.recipients[0], s.sender.Public, envelope, []byte("other"), nil)
+		s.True(errors.Is(err, ErrAuthentication), "wrong additional data accepted: %v", err)
+	}
+
+	envelope, err := SealMulti(s.sender, s.publicKeys()[1:2], nil, nil, nil)
+	s.Require().NoError(err, "failed to seal for a single recipient")
+	opened, err := OpenMulti(s.recipients[1], s.sender.Public, envelope, nil, nil)
+	s.Require().NoError(err, "failed to open for a single recipient")
+	s.Empty(opened, "plaintext not empty")
+}
+
+func (s *MultiTestSuite) TestRecipients() {
+	_, err := SealMulti(s.sender, nil, nil, nil, nil)
+	s.Error(err, "missing recipients accepted")
+	_, err = SealMulti(s.sender, []*PublicKey{s.recipients[0].Public, s.recipients[0].Public}, nil, nil, nil)
+	s.Error(err, "duplicate recipient accepted")
+
+	envelope, err := SealMulti(s.sender, s.publicKeys()[:2], []byte("secret"), nil, nil)
+	s.Require().NoError(err, "failed to seal")
+	_, err = OpenMulti(s.recipients[2], s.sender.Public, envelope, nil, nil)
+	s.True(errors.Is(err, ErrUnknownKey), "missing stanza accepted: %v", err)
+
+	other, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	_, err = OpenMulti(s.recipients[0], other.Public, envelope, nil, nil)
+	s.True(errors.Is(err, ErrAuthentication), "wrong sender accepted: %v", err)
+}
+
+func (s *MultiTestSuite) TestModified() {
+	envelope, err := SealMulti(s.sender, s.publicKeys(), []byte("secret"), nil, nil)
+	s.Require().NoError(err, "failed to seal")
+
+	for i := range envelope {
+		modified := append([]byte(nil), envelope...)
+		modified[i] ^= 1
+		_, err := OpenMulti(s.recipients[1], s.sender.Public, modified, nil, nil)
+		s.Errorf(err, "modified byte %d accepted", i)
+	}
+	for i := 0; i < len(envelope); i++ {
+		_, err := OpenMulti(s.recipients[1], s.sender.Public, envelope[:i], nil, nil)
+		s.Errorf(err, "truncated envelope of length %d accepted", i)
+	}
+}
+
+func TestMultiP224(t *testing.T) {
+	suite.Run(t, &MultiTestSuite{Curve: elliptic.P224()})
+}
+
+func TestMultiP256(t *testing.T) {
+	suite.Run(t, &MultiTestSuite{Curve: elliptic.P256()})
+}
+
+func TestMultiP384(t *testing.T) {
+	suite.Run(t, &MultiTestSuite{Curve: elliptic.P384()})
+}
+
+func TestMultiP521(t *testing.T) {
+	suite.Run(t, &MultiTestSuite{Curve: elliptic.P521()})
+}
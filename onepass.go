@@ -0,0 +1,68 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// OnePassInitiate runs the sender side of the one-pass MQV key agreement
+// C(1e, 2s). It generates a new ephemeral key pair on the curve of the own
+// static key and calculates the shared secret with BlindAgree, using the
+// static public key of the receiver twice (as static and as ephemeral key).
+// The static public key of the receiver is validated fully. The ephemeral
+// public key is returned and has to be sent to the receiver, the ephemeral
+// private key is wiped.
+func OnePassInitiate(ownStatic *KeyPair, peerStatic *PublicKey, rand io.Reader) (*PublicKey, *SharedSecret, error) {
+	if ownStatic == nil || ownStatic.Private == nil || ownStatic.Public == nil {
+		return nil, nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	if err := ValidatePublicKeyFull(peerStatic); err != nil {
+		return nil, nil, fmt.Errorf("failed to validate static key: %w", err)
+	}
+
+	ephemeral, err := GenerateKeyPair(ownStatic.Private.Curve, rand)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate ephemeral key: %w", err)
+	}
+	defer WipeBytes(ephemeral.Private.D)
+
+	z, err := BlindAgree(ownStatic, ephemeral, peerStatic, peerStatic, rand)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to calculate shared secret: %w", err)
+	}
+	return ephemeral.Public, z, nil
+}
+
+// OnePassRespond runs the receiver side of the one-pass MQV key agreement
+// C(1e, 2s). It calculates the shared secret with BlindAgree from the own
+// static key pair, which is used twice (as static and as ephemeral key), and
+// the static and ephemeral public keys of the sender. The static public key
+// of the sender is validated fully and its ephemeral public key partially.
+// An error is returned if the ephemeral public key equals one of the static
+// public keys, which usually means that the arguments were mixed up.
+func OnePassRespond(ownStatic *KeyPair, peerStatic, peerEphemeral *PublicKey, rand io.Reader) (*SharedSecret, error) {
+	if ownStatic == nil || ownStatic.Private == nil || ownStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	if err := validatePeerKeys(peerStatic, peerEphemeral); err != nil {
+		return nil, err
+	}
+	if equalPublicKeys(peerEphemeral, peerStatic) || equalPublicKeys(peerEphemeral, ownStatic.Public) {
+		return nil, errors.New("ephemeral key equals a static key")
+	}
+
+	z, err := BlindAgree(ownStatic, ownStatic, peerStatic, peerEphemeral, rand)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate shared secret: %w", err)
+	}
+	return z, nil
+}
+
+// equalPublicKeys reports whether a and b are the same point.
+func equalPublicKeys(a, b *PublicKey) bool {
+	return a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type OnePassTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	sender, receiver *KeyPair
+}
+
+func (s *OnePassTestSuite) SetupTest() {
+	var err error
+	s.sender, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the sender")
+	s.receiver, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the receiver")
+}
+
+func (s *OnePassTestSuite) TestAgree() {
+	ephemeral, zU, err := OnePassInitiate(s.sender, s.receiver.Public, rand.Reader)
+	s.Require().NoError(err, "failed to initiate")
+	s.NoError(ValidatePublicKeyFull(ephemeral), "invalid ephemeral key")
+
+	zV, err := OnePassRespond(s.receiver, s.sender.Public, ephemeral, rand.Reader)
+	s.Require().NoError(err, "failed to respond")
+	s.True(zU.Equal(zV), "shared secrets are not equal")
+
+	// the scheme uses the same key agreement
+	ref, err := Agree(s.receiver, s.receiver, s.sender.Public, ephemeral)
+	s.Require().NoError(err, "failed to run agree")
+	s.True(ref.Equal(zV), "shared secret differs from agree")
+
+	ephemeral2, zU2, err := OnePassInitiate(s.sender, s.receiver.Public, rand.Reader)
+	s.Require().NoError(err, "failed to initiate twice")
+	s.NotEqual(ephemeral.X.Text(16), ephemeral2.X.Text(16), "ephemeral key reused")
+	s.False(zU.Equal(zU2), "shared secret reused")
+}
+
+func (s *OnePassTestSuite) TestArgumentOrder() {
+	ephemeral, zU, err := OnePassInitiate(s.sender, s.receiver.Public, rand.Reader)
+	s.Require().NoError(err, "failed to initiate")
+
+	zV, err := OnePassRespond(s.receiver, ephemeral, s.sender.Public, rand.Reader)
+	s.Require().NoError(err, "failed to respond with swapped public keys")
+	s.False(zU.Equal(zV), "swapped public keys result in the same shared secret")
+
+	_, err = OnePassRespond(s.receiver, s.sender.Public, s.sender.Public, rand.Reader)
+	s.Error(err, "static key of the sender accepted as ephemeral key")
+	_, err = OnePassRespond(s.receiver, s.sender.Public, s.receiver.Public, rand.Reader)
+	s.Error(err, "own static key accepted as ephemeral key")
+	_, err = OnePassRespond(nil, s.sender.Public, ephemeral, rand.Reader)
+	s.Error(err, "missing key pair accepted")
+}
+
+func (s *OnePassTestSuite) TestInvalidPublicKey() {
+	invalid := &PublicKey{Curve: s.Curve, X: s.receiver.Public.X, Y: new(big.Int).Add(s.receiver.Public.Y, one)}
+	_, _, err := OnePassInitiate(s.sender, invalid, rand.Reader)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid static key accepted: %v", err)
+
+	_, err = OnePassRespond(s.receiver, s.sender.Public, invalid, rand.Reader)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid ephemeral key accepted: %v", err)
+}
+
+func TestOnePassP224(t *testing.T) {
+	suite.Run(t, &OnePassTestSuite{Curve: elliptic.P224()})
+}
+
+func TestOnePassP256(t *testing.T) {
+	suite.Run(t, &OnePassTestSuite{Curve: elliptic.P256()})
+}
+
+func TestOnePassP384(t *testing.T) {
+	suite.Run(t, &OnePassTestSuite{Curve: elliptic.P384()})
+}
+
+func TestOnePassP521(t *testing.T) {
+	suite.Run(t, &OnePassTestSuite{Curve: elliptic.P521()})
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// refPoint is an affine point of the naive reference implementation. The
+// point at infinity is represented by nil.
+type refPoint struct {
+	x, y *big.Int
+}
+
+// refAdd adds two points using the textbook formulas for curves with a = -3.
+func refAdd(params *elliptic.CurveParams, p, q *refPoint) *refPoint {
+	if p == nil {
+		return q
+	}
+	if q == nil {
+		return p
+	}
+	var lambda *big.Int
+	if p.x.Cmp(q.x) == 0 {
+		if new(big.Int).Add(p.y, q.y).Cmp(params.P) == 0 || p.y.Sign() == 0 {
+			return nil
+		}
+		// lambda = (3x^2 - 3) / 2y
+		num := new(big.Int).Mul(p.x, p.x)
+		num.Sub(num, one)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(p.y, 1)
+		lambda = num.Mul(num, den.ModInverse(den.Mod(den, params.P), params.P))
+	} else {
+		// lambda = (y2 - y1) / (x2 - x1)
+		num := new(big.Int).Sub(q.y, p.y)
+		den := new(big.Int).Sub(q.x, p.x)
+		lambda = num.Mul(num, den.ModInverse(den.Mod(den, params.P), params.P))
+	}
+	lambda.Mod(lambda, params.P)
+
+	x := new(big.Int).Mul(lambda, lambda)
+	x.Sub(x, p.x)
+	x.Sub(x, q.x)
+	x.Mod(x, params.P)
+	y := new(big.Int).Sub(p.x, x)
+	y.Mul(y, lambda)
+	y.Sub(y, p.y)
+	y.Mod(y, params.P)
+	return &refPoint{x, y}
+}
+
+// refScalarMult calculates k * p with double-and-add.
+func refScalarMult(params *elliptic.CurveParams, p *refPoint, k *big.Int) *refPoint {
+	var r *refPoint
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = refAdd(params, r, r)
+		if k.Bit(i) == 1 {
+			r = refAdd(params, r, p)
+		}
+	}
+	return r
+}
+
+// refAvf implements the associative value function of section 5.7.2.2.
+func refAvf(x *big.Int, params *elliptic.CurveParams) *big.Int {
+	f := params.N.BitLen()
+	b := new(big.Int).Lsh(one, uint((f+1)/2))
+	v := new(big.Int).Mod(x, b)
+	return v.Add(v, b)
+}
+
+// refMQV is a naive implementation of the ECC MQV primitive of section
+// 5.7.2.3 that uses math/big only. h is the cofactor of the curve.
+func refMQV(ownStaticPriv, ownEphemeralPriv []byte, ownEphemeralX, otherStaticX, otherStaticY, otherEphemeralX, otherEphemeralY *big.Int, params *elliptic.CurveParams, h *big.Int) (*big.Int, *big.Int, error) {
+	ds := new(big.Int).SetBytes(ownStaticPriv)
+	de := new(big.Int).SetBytes(ownEphemeralPriv)
+
+	implSig := refAvf(ownEphemeralX, params)
+	implSig.Mul(implSig, ds)
+	implSig.Add(implSig, de)
+	implSig.Mod(implSig, params.N)
+	implSig.Mul(implSig, h)
+
+	qs := &refPoint{otherStaticX, otherStaticY}
+	qe := &refPoint{otherEphemeralX, otherEphemeralY}
+	base := refAdd(params, qe, refScalarMult(params, qs, refAvf(otherEphemeralX, params)))
+	p := refScalarMult(params, base, implSig)
+	if p == nil {
+		return nil, nil, ErrIdentity
+	}
+	return p.x, p.y, nil
+}
+
+type ReferenceTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+}
+
+// keyPair returns the key pair with the private key d.
+func (s *ReferenceTestSuite) keyPair(d *big.Int) *KeyPair {
+	priv, err := NewPrivateKey(s.Curve, d.Bytes())
+	s.Require().NoError(err, "invalid private key")
+	return NewKeyPair(priv)
+}
+
+func (s *ReferenceTestSuite) randomKeyPair() *KeyPair {
+	kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	return kp
+}
+
+// check runs MQV, BlindMQV with all blind modes and the reference
+// implementation and asserts that all of them return the same point or the
+// same error.
+func (s *ReferenceTestSuite) check(name string, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) {
+	s.T().Helper()
+	ds, de, xe := ownStatic.Private.D, ownEphemeral.Private.D, ownEphemeral.Public.X
+
+	h, err := cofactor(s.Curve)
+	s.Require().NoError(err, "unsupported curve")
+
+	refX, refY, refErr := refMQV(ds, de, xe, otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, s.Curve.Params(), h)
+	x, y, err := MQV(ds, de, xe, otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, s.Curve)
+	blindX, blindY, blindErr := BlindMQV(ds, de, xe, otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, s.Curve, rand.Reader)
+
+	s.Equalf(refErr, err, "%s: mqv error differs", name)
+	s.Equalf(refErr, blindErr, "%s: blind mqv error differs", name)
+	if refErr == nil && err == nil && blindErr == nil {
+		s.Equalf(refX.Text(16), x.Text(16), "%s: mqv x differs", name)
+		s.Equalf(refY.Text(16), y.Text(16), "%s: mqv y differs", name)
+		s.Equalf(refX.Text(16), blindX.Text(16), "%s: blind mqv x differs", name)
+		s.Equalf(refY.Text(16), blindY.Text(16), "%s: blind mqv y differs", name)
+	}
+
+	for _, mode := range []BlindMode{BlindMultiplicative, BlindPoint, BlindMultiplicative | BlindPoint} {
+		blindX, blindY, blindErr = BlindMQV(ds, de, xe, otherStatic.X, otherStatic.Y, otherEphemeral.X, otherEphemeral.Y, s.Curve, rand.Reader, mode)
+		s.Equalf(refErr, blindErr, "%s: blind mqv error differs for mode %d", name, mode)
+		if refErr == nil && blindErr == nil {
+			s.Equalf(refX.Text(16), blindX.Text(16), "%s: blind mqv x differs for mode %d", name, mode)
+			s.Equalf(refY.Text(16), blindY.Text(16), "%s: blind mqv y differs for mode %d", name, mode)
+		}
+	}
+}
+
+func (s *ReferenceTestSuite) TestRandom() {
+	for i := 0; i < 4; i++ {
+		s.check("random", s.randomKeyPair(), s.randomKeyPair(), s.randomKeyPair().Public, s.randomKeyPair().Public)
+	}
+}
+
+func (s *ReferenceTestSuite) TestEdgeScalars() {
+	n := s.Curve.Params().N
+	nm1 := s.keyPair(new(big.Int).Sub(n, one))
+	nm2 := s.keyPair(new(big.Int).Sub(n, big.NewInt(2)))
+	k1 := s.keyPair(one)
+	other := s.randomKeyPair()
+
+	s.check("n-1 own keys", nm1, nm1, other.Public, other.Public)
+	s.check("n-1 and n-2 own keys", nm1, nm2, other.Public, s.randomKeyPair().Public)
+	s.check("n-1 other keys", other, s.randomKeyPair(), nm1.Public, nm2.Public)
+	s.check("one own keys", k1, k1, nm1.Public, nm1.Public)
+	s.check("one and n-1", k1, nm1, k1.Public, nm1.Public)
+}
+
+func (s *ReferenceTestSuite) TestIdenticalKeys() {
+	own := s.randomKeyPair()
+	other := s.randomKeyPair()
+
+	s.check("identical keys", own, own, other.Public, other.Public)
+	s.check("same party", own, own, own.Public, own.Public)
+}
+
+func (s *ReferenceTestSuite) TestOnePass() {
+	sender := s.randomKeyPair()
+	ephemeral := s.randomKeyPair()
+	receiver := s.randomKeyPair()
+
+	s.check("one-pass sender", sender, ephemeral, receiver.Public, receiver.Public)
+	s.check("one-pass receiver", receiver, receiver, sender.Public, ephemeral.Public)
+}
+
+func (s *ReferenceTestSuite) TestIdentity() {
+	// Choose the static key of the other party such that
+	// Qe + avf(Qe) * Qs is the point at infinity.
+	params := s.Curve.Params()
+	ephemeral := s.randomKeyPair()
+	k := refAvf(ephemeral.Public.X, params)
+	k.ModInverse(k, params.N)
+	k.Sub(params.N, k)
+	x, y := s.Curve.ScalarMult(ephemeral.Public.X, ephemeral.Public.Y, k.Bytes())
+	static := &PublicKey{Curve: s.Curve, X: x, Y: y}
+
+	s.check("identity", s.randomKeyPair(), s.randomKeyPair(), static, ephemeral.Public)
+
+	_, _, err := MQV(ephemeral.Private.D, ephemeral.Private.D, ephemeral.Public.X, x, y, ephemeral.Public.X, ephemeral.Public.Y, s.Curve)
+	s.Equal(ErrIdentity, err, "identity not detected")
+}
+
+func TestReferenceP224(t *testing.T) {
+	suite.Run(t, &ReferenceTestSuite{Curve: elliptic.P224()})
+}
+
+func TestReferenceP256(t *testing.T) {
+	suite.Run(t, &ReferenceTestSuite{Curve: elliptic.P256()})
+}
+
+func TestReferenceP384(t *testing.T) {
+	suite.Run(t, &ReferenceTestSuite{Curve: elliptic.P384()})
+}
+
+func TestReferenceP521(t *testing.T) {
+	suite.Run(t, &ReferenceTestSuite{Curve: elliptic.P521()})
+}
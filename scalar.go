@@ -0,0 +1,397 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"io"
+	"math/big"
+	"sync"
+)
+
+// scalarMult calculates k * (x, y). The standard library curves P-224,
+// P-256, P-384 and P-521 are constant time, so their implementation is used
+// directly. Curves defined by elliptic.CurveParams use the constant time
+// Montgomery ladder of this package instead of the variable time generic
+// implementation of crypto/elliptic, as does WeierstrassCurve. Any other
+// implementation of elliptic.Curve is trusted to be constant time.
+func scalarMult(curve elliptic.Curve, x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	switch curve {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return curve.ScalarMult(x, y, k)
+	}
+	if c, ok := jacobianFor(curve); ok {
+		return c.scalarMult(x, y, k, nil)
+	}
+	return curve.ScalarMult(x, y, k)
+}
+
+// addPoints calculates (x1, y1) + (x2, y2). Like scalarMult, the standard
+// library curves are used directly and all other curves with known
+// coefficients use the constant time addition of this package, since the
+// generic implementation of crypto/elliptic is variable time. This matters
+// for the sum of the two partial results of a blinded scalar multiplication,
+// which depend on the secret scalar. The point at infinity (0, 0) is
+// returned if a coordinate is out of range.
+func addPoints(curve elliptic.Curve, x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	switch curve {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+		return curve.Add(x1, y1, x2, y2)
+	}
+	c, ok := jacobianFor(curve)
+	if !ok {
+		return curve.Add(x1, y1, x2, y2)
+	}
+	p1 := c.newPoint()
+	defer p1.wipe()
+	p2 := c.newPoint()
+	defer p2.wipe()
+	if !c.setAffine(p1, x1, y1) || !c.setAffine(p2, x2, y2) {
+		return new(big.Int), new(big.Int)
+	}
+	c.add(p1, p1, p2)
+	return c.affine(p1)
+}
+
+// scalarMultMode calculates k * (x, y) like scalarMult. If BlindPoint is
+// set, the Montgomery ladder of this package is used for all curves and the
+// point is randomized with a random Z coordinate.
+func scalarMultMode(curve elliptic.Curve, x, y *big.Int, k []byte, rand io.Reader, mode BlindMode) (*big.Int, *big.Int, error) {
+	if mode&BlindPoint == 0 {
+		rx, ry := scalarMult(curve, x, y, k)
+		return rx, ry, nil
+	}
+	c, ok := jacobianFor(curve)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q: point blinding is not supported", ErrUnsupportedCurve, curve.Params().Name)
+	}
+	lambda, err := generateScalar(curve.Params().P, rand)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate random z coordinate: %w", err)
+	}
+	defer WipeBytes(lambda)
+	rx, ry := c.scalarMult(x, y, k, lambda)
+	return rx, ry, nil
+}
+
+// jacobianCurves caches the curve arithmetic of the curves with a = -3 by
+// their parameters, since setting up the Montgomery context is expensive.
+// Like the registry, it assumes that the parameters are not modified.
+var jacobianCurves sync.Map // map[*elliptic.CurveParams]*jacobianCurve
+
+// jacobianFor returns the curve arithmetic of this package for curve. This
+// is only possible for curves with known coefficients, i.e. instances of
+// WeierstrassCurve, the standard library curves and curves defined by
+// elliptic.CurveParams, which all have a = -3.
+func jacobianFor(curve elliptic.Curve) (*jacobianCurve, bool) {
+	if c, ok := curve.(*WeierstrassCurve); ok {
+		return c.jac, true
+	}
+	switch curve {
+	case elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521():
+	default:
+		if _, ok := curve.(*elliptic.CurveParams); !ok {
+			return nil, false
+		}
+	}
+	params := curve.Params()
+	if c, ok := jacobianCurves.Load(params); ok {
+		return c.(*jacobianCurve), true
+	}
+	a := new(big.Int).Sub(params.P, big.NewInt(3))
+	c, _ := jacobianCurves.LoadOrStore(params, newJacobianCurve(params.P, a))
+	return c.(*jacobianCurve), true
+}
+
+// jacobianCurve implements constant time arithmetic on a short Weierstrass
+// curve y^2 = x^3 + a*x + b over the prime field GF(p). Points are stored
+// in Jacobian coordinates (X, Y, Z), which represent the affine point
+// (X/Z^2, Y/Z^3). All coordinates are kept in the Montgomery domain of p.
+// The point at infinity is represented by Z = 0. The coefficient b is not
+// required by the formulas.
+type jacobianCurve struct {
+	m *MontContext
+	p *big.Int
+	a SubtleInt // a*R mod p
+}
+
+// jacobianPoint is a point in Jacobian coordinates.
+type jacobianPoint struct {
+	x, y, z SubtleInt
+}
+
+// newJacobianCurve returns the curve arithmetic for the odd prime p and the
+// coefficient 0 <= a < p.
+func newJacobianCurve(p, a *big.Int) *jacobianCurve {
+	size := SubtleIntSize(p.BitLen())
+	c := &jacobianCurve{
+		m: NewMontContext(subtleFromBytes(p.Bytes(), size)),
+		p: new(big.Int).Set(p),
+		a: make(SubtleInt, size),
+	}
+	c.m.ToMont(c.a, subtleFromBytes(a.Bytes(), size))
+	return c
+}
+
+// newPoint returns a new point at infinity.
+func (c *jacobianCurve) newPoint() *jacobianPoint {
+	k := c.m.Size()
+	return &jacobianPoint{
+		x: make(SubtleInt, k),
+		y: make(SubtleInt, k),
+		z: make(SubtleInt, k),
+	}
+}
+
+// wipe overrides all coordinates with zeros.
+func (p *jacobianPoint) wipe() {
+	p.x.SetZero()
+	p.y.SetZero()
+	p.z.SetZero()
+}
+
+// swap exchanges the points p and q if c = 1 and does nothing if c = 0.
+func (p *jacobianPoint) swap(c uint, q *jacobianPoint) {
+	p.x.swap(c, q.x)
+	p.y.swap(c, q.y)
+	p.z.swap(c, q.z)
+}
+
+// selectPoint sets p to q if c = 1 and r if c = 0.
+func (p *jacobianPoint) selectPoint(c uint, q, r *jacobianPoint) {
+	p.x.Select(c, q.x, r.x)
+	p.y.Select(c, q.y, r.y)
+	p.z.Select(c, q.z, r.z)
+}
+
+// isZero returns 1 if x is zero and 0 otherwise.
+func isZero(x SubtleInt) uint {
+	var acc uint
+	for _, w := range x {
+		acc |= w
+	}
+	return equalW(acc, 0)
+}
+
+// setAffine sets p to the affine point (x, y). The point (0, 0) is mapped to
+// the point at infinity. If a coordinate is not in the range [0, p-1], p is
+// set to the point at infinity and false is returned. The coordinates are
+// public, so the range check does not need to be constant time.
+func (c *jacobianCurve) setAffine(p *jacobianPoint, x, y *big.Int) bool {
+	k := c.m.Size()
+	if x.Sign() < 0 || x.Cmp(c.p) >= 0 || y.Sign() < 0 || y.Cmp(c.p) >= 0 {
+		p.wipe()
+		return false
+	}
+	xInt := subtleFromBytes(x.Bytes(), k)
+	yInt := subtleFromBytes(y.Bytes(), k)
+	inf := isZero(xInt) & isZero(yInt)
+	c.m.ToMont(p.x, xInt)
+	c.m.ToMont(p.y, yInt)
+	c.m.One(p.z)
+	p.z.Select(inf, make(SubtleInt, k), p.z)
+	return true
+}
+
+// affine returns the affine coordinates of p. The point at infinity is
+// returned as (0, 0), like crypto/elliptic does.
+func (c *jacobianCurve) affine(p *jacobianPoint) (*big.Int, *big.Int) {
+	k := c.m.Size()
+	zInv := make(SubtleInt, k)
+	defer zInv.SetZero()
+	t := make(SubtleInt, k)
+	defer t.SetZero()
+
+	// The inverse of Z = 0 is 0, so the point at infinity becomes (0, 0).
+	c.m.FromMont(t, p.z)
+	zInv.ModInverse(t, c.m.n)
+	c.m.ToMont(zInv, zInv)
+
+	x := make(SubtleInt, k)
+	defer x.SetZero()
+	y := make(SubtleInt, k)
+	defer y.SetZero()
+	c.m.MontSquare(t, zInv)
+	c.m.MontMul(x, p.x, t)
+	c.m.MontMul(t, t, zInv)
+	c.m.MontMul(y, p.y, t)
+	c.m.FromMont(x, x)
+	c.m.FromMont(y, y)
+	return x.Big(), y.Big()
+}
+
+// double sets r to 2 * p. It is correct for all inputs, including the point
+// at infinity and points of order two.
+func (c *jacobianCurve) double(r, p *jacobianPoint) {
+	m, n := c.m, c.m.n
+	k := m.Size()
+	xx := make(SubtleInt, k)
+	defer xx.SetZero()
+	yy := make(SubtleInt, k)
+	defer yy.SetZero()
+	zz := make(SubtleInt, k)
+	defer zz.SetZero()
+	s := make(SubtleInt, k)
+	defer s.SetZero()
+	t := make(SubtleInt, k)
+	defer t.SetZero()
+	x3 := make(SubtleInt, k)
+	defer x3.SetZero()
+
+	m.MontSquare(xx, p.x)
+	m.MontSquare(yy, p.y)
+	m.MontSquare(zz, p.z)
+
+	// s = 4 * x * yy
+	m.MontMul(s, p.x, yy)
+	s.AddMod(s, s, n)
+	s.AddMod(s, s, n)
+
+	// t = 3 * xx + a * zz^2
+	m.MontSquare(zz, zz)
+	m.MontMul(zz, zz, c.a)
+	t.AddMod(xx, xx, n)
+	t.AddMod(t, xx, n)
+	t.AddMod(t, zz, n)
+
+	// x3 = t^2 - 2 * s
+	m.MontSquare(x3, t)
+	x3.SubMod(x3, s, n)
+	x3.SubMod(x3, s, n)
+
+	// z3 = 2 * y * z
+	m.MontMul(r.z, p.y, p.z)
+	r.z.AddMod(r.z, r.z, n)
+
+	// y3 = t * (s - x3) - 8 * yy^2
+	m.MontSquare(yy, yy)
+	yy.AddMod(yy, yy, n)
+	yy.AddMod(yy, yy, n)
+	yy.AddMod(yy, yy, n)
+	s.SubMod(s, x3, n)
+	m.MontMul(r.y, t, s)
+	r.y.SubMod(r.y, yy, n)
+	copy(r.x, x3)
+}
+
+// add sets r to p + q. All special cases (either point is the point at
+// infinity, p = q and p = -q) are handled without branches on secret data.
+func (c *jacobianCurve) add(r, p, q *jacobianPoint) {
+	m, n := c.m, c.m.n
+	k := m.Size()
+	u1 := make(SubtleInt, k)
+	defer u1.SetZero()
+	u2 := make(SubtleInt, k)
+	defer u2.SetZero()
+	s1 := make(SubtleInt, k)
+	defer s1.SetZero()
+	s2 := make(SubtleInt, k)
+	defer s2.SetZero()
+	t := make(SubtleInt, k)
+	defer t.SetZero()
+
+	// u1 = x1 * z2^2, s1 = y1 * z2^3
+	m.MontSquare(t, q.z)
+	m.MontMul(u1, p.x, t)
+	m.MontMul(t, t, q.z)
+	m.MontMul(s1, p.y, t)
+
+	// u2 = x2 * z1^2, s2 = y2 * z1^3
+	m.MontSquare(t, p.z)
+	m.MontMul(u2, q.x, t)
+	m.MontMul(t, t, p.z)
+	m.MontMul(s2, q.y, t)
+
+	// h = u2 - u1, rr = s2 - s1
+	h, rr := u2, s2
+	h.SubMod(u2, u1, n)
+	rr.SubMod(s2, s1, n)
+	isDouble := isZero(h) & isZero(rr)
+
+	sum := c.newPoint()
+	defer sum.wipe()
+
+	// z3 = z1 * z2 * h, which is zero if p = -q
+	m.MontMul(sum.z, p.z, q.z)
+	m.MontMul(sum.z, sum.z, h)
+
+	// x3 = rr^2 - h^3 - 2 * u1 * h^2
+	hh := make(SubtleInt, k)
+	defer hh.SetZero()
+	m.MontSquare(hh, h)
+	m.MontMul(u1, u1, hh)
+	m.MontMul(hh, hh, h)
+	m.MontSquare(sum.x, rr)
+	sum.x.SubMod(sum.x, hh, n)
+	sum.x.SubMod(sum.x, u1, n)
+	sum.x.SubMod(sum.x, u1, n)
+
+	// y3 = rr * (u1 * h^2 - x3) - s1 * h^3
+	u1.SubMod(u1, sum.x, n)
+	m.MontMul(sum.y, rr, u1)
+	m.MontMul(t, s1, hh)
+	sum.y.SubMod(sum.y, t, n)
+
+	dbl := c.newPoint()
+	defer dbl.wipe()
+	c.double(dbl, p)
+
+	pInf := isZero(p.z)
+	qInf := isZero(q.z)
+	sum.selectPoint(isDouble&^pInf&^qInf, dbl, sum)
+	sum.selectPoint(qInf, p, sum)
+	sum.selectPoint(pInf, q, sum)
+	copy(r.x, sum.x)
+	copy(r.y, sum.y)
+	copy(r.z, sum.z)
+}
+
+// randomize changes the representation of p to (X * l^2, Y * l^3, Z * l),
+// which is the same point for any l in [1, p-1]. l is a big-endian byte
+// slice.
+func (c *jacobianCurve) randomize(p *jacobianPoint, l []byte) {
+	k := c.m.Size()
+	lm := make(SubtleInt, k)
+	defer lm.SetZero()
+	c.m.ToMont(lm, subtleFromBytes(l, k))
+	t := make(SubtleInt, k)
+	defer t.SetZero()
+
+	c.m.MontMul(p.z, p.z, lm)
+	c.m.MontSquare(t, lm)
+	c.m.MontMul(p.x, p.x, t)
+	c.m.MontMul(t, t, lm)
+	c.m.MontMul(p.y, p.y, t)
+}
+
+// scalarMult calculates k * (x, y) with a Montgomery ladder. The number of
+// iterations only depends on the length of k and every iteration executes
+// exactly one addition and one doubling. If l is not nil, the projective
+// representation of (x, y) is randomized with it first, see randomize. The
+// point at infinity (0, 0) is returned if a coordinate is out of range.
+func (c *jacobianCurve) scalarMult(x, y *big.Int, k, l []byte) (*big.Int, *big.Int) {
+	r0 := c.newPoint()
+	defer r0.wipe()
+	r1 := c.newPoint()
+	defer r1.wipe()
+	if !c.setAffine(r1, x, y) {
+		return new(big.Int), new(big.Int)
+	}
+	if l != nil {
+		c.randomize(r1, l)
+	}
+
+	// invariant: r1 - r0 = (x, y)
+	for _, b := range k {
+		for i := 7; i >= 0; i-- {
+			bit := uint(b>>uint(i)) & 1
+			r0.swap(bit, r1)
+			c.add(r1, r0, r1)
+			c.double(r0, r0)
+			r0.swap(bit, r1)
+		}
+	}
+	return c.affine(r0)
+}
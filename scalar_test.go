@@ -0,0 +1,184 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScalarTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	generic *elliptic.CurveParams // copy of the parameters, not optimized by crypto/elliptic
+}
+
+func (s *ScalarTestSuite) SetupTest() {
+	params := *s.Curve.Params()
+	s.generic = &params
+}
+
+func (s *ScalarTestSuite) check(name string, x, y *big.Int, k []byte) {
+	wantX, wantY := s.Curve.ScalarMult(x, y, k)
+	gotX, gotY := scalarMult(s.generic, x, y, k)
+	s.Equalf(wantX.Text(16), gotX.Text(16), "x is not equal for %s", name)
+	s.Equalf(wantY.Text(16), gotY.Text(16), "y is not equal for %s", name)
+}
+
+func (s *ScalarTestSuite) TestRandom() {
+	params := s.Curve.Params()
+	for i := 0; i < 4; i++ {
+		k, err := GenerateKey(params, rand.Reader)
+		s.Require().NoError(err, "failed to generate scalar")
+		s.check("base point", params.Gx, params.Gy, k)
+
+		d, err := GenerateKey(params, rand.Reader)
+		s.Require().NoError(err, "failed to generate private key")
+		x, y := s.Curve.ScalarBaseMult(d)
+		s.check("random point", x, y, k)
+	}
+}
+
+func (s *ScalarTestSuite) TestEdgeScalars() {
+	params := s.Curve.Params()
+	n := params.N
+	scalars := map[string]*big.Int{
+		"0":       big.NewInt(0),
+		"1":       big.NewInt(1),
+		"2":       big.NewInt(2),
+		"(n-1)/2": new(big.Int).Rsh(n, 1),
+		"(n+1)/2": new(big.Int).Rsh(new(big.Int).Add(n, one), 1),
+		"n-1":     new(big.Int).Sub(n, one),
+		"n":       n,
+		"n+1":     new(big.Int).Add(n, one),
+		"2n+3":    new(big.Int).Add(new(big.Int).Lsh(n, 1), big.NewInt(3)),
+	}
+	for name, k := range scalars {
+		s.check(name, params.Gx, params.Gy, k.Bytes())
+
+		// leading zeros must not change the result
+		padded := append(make([]byte, 3), k.Bytes()...)
+		s.check(name+" padded", params.Gx, params.Gy, padded)
+	}
+}
+
+func (s *ScalarTestSuite) TestInfinity() {
+	x, y := scalarMult(s.generic, new(big.Int), new(big.Int), []byte{0x12, 0x34})
+	s.True(isInfinity(x, y), "multiple of the point at infinity is not the point at infinity")
+}
+
+func (s *ScalarTestSuite) TestScalarMultBlind() {
+	params := s.Curve.Params()
+	k, err := GenerateKey(params, rand.Reader)
+	s.Require().NoError(err, "failed to generate scalar")
+
+	wantX, wantY := s.Curve.ScalarMult(params.Gx, params.Gy, k)
+	x, y, err := ScalarMultBlind(params.Gx, params.Gy, k, s.generic, rand.Reader)
+	s.Require().NoError(err, "failed to run blinded scalar multiplication")
+	s.Equal(wantX.Text(16), x.Text(16), "x is not equal")
+	s.Equal(wantY.Text(16), y.Text(16), "y is not equal")
+}
+
+func (s *ScalarTestSuite) TestScalarMultBlindModes() {
+	params := s.Curve.Params()
+	k, err := GenerateKey(params, rand.Reader)
+	s.Require().NoError(err, "failed to generate scalar")
+	wantX, wantY := s.Curve.ScalarMult(params.Gx, params.Gy, k)
+
+	for _, curve := range []elliptic.Curve{s.Curve, s.generic} {
+		for _, mode := range []BlindMode{BlindMultiplicative, BlindPoint, BlindMultiplicative | BlindPoint} {
+			x, y, err := ScalarMultBlind(params.Gx, params.Gy, k, curve, rand.Reader, mode)
+			s.Require().NoErrorf(err, "failed to run blinded scalar multiplication with mode %d", mode)
+			s.Equalf(wantX.Text(16), x.Text(16), "x is not equal for mode %d", mode)
+			s.Equalf(wantY.Text(16), y.Text(16), "y is not equal for mode %d", mode)
+		}
+	}
+}
+
+func (s *ScalarTestSuite) TestAddPoints() {
+	params := s.Curve.Params()
+	k, err := GenerateKey(params, rand.Reader)
+	s.Require().NoError(err, "failed to generate scalar")
+	x, y := s.Curve.ScalarBaseMult(k)
+	negY := new(big.Int).Sub(params.P, y)
+
+	points := map[string][4]*big.Int{
+		"random":   {params.Gx, params.Gy, x, y},
+		"double":   {x, y, x, y},
+		"inverse":  {x, y, x, negY},
+		"infinity": {x, y, new(big.Int), new(big.Int)},
+	}
+	for name, p := range points {
+		wantX, wantY := s.Curve.Add(p[0], p[1], p[2], p[3])
+		gotX, gotY := addPoints(s.generic, p[0], p[1], p[2], p[3])
+		s.Equalf(wantX.Text(16), gotX.Text(16), "x is not equal for %s", name)
+		s.Equalf(wantY.Text(16), gotY.Text(16), "y is not equal for %s", name)
+	}
+}
+
+func (s *ScalarTestSuite) TestOutOfRange() {
+	params := s.Curve.Params()
+	// 2^300 for P-256, which has more bytes than p
+	oversized := new(big.Int).Lsh(one, uint(params.BitSize+44))
+	k := []byte{1}
+
+	for name, p := range map[string][2]*big.Int{
+		"oversized": {oversized, params.Gy},
+		"p":         {params.Gx, params.P},
+		"-1":        {big.NewInt(-1), params.Gy},
+	} {
+		x, y := scalarMult(s.generic, p[0], p[1], k)
+		s.Truef(isInfinity(x, y), "scalar multiple of %s is not the point at infinity", name)
+		x, y = addPoints(s.generic, p[0], p[1], params.Gx, params.Gy)
+		s.Truef(isInfinity(x, y), "sum with %s is not the point at infinity", name)
+
+		_, _, err := ScalarMultBlind(p[0], p[1], k, s.generic, rand.Reader)
+		s.Truef(errors.Is(err, ErrInvalidPublicKey), "point with %s accepted: %v", name, err)
+	}
+}
+
+func (s *ScalarTestSuite) TestJacobianCache() {
+	c1, ok := jacobianFor(s.generic)
+	s.Require().True(ok, "curve not supported")
+	c2, ok := jacobianFor(s.generic)
+	s.Require().True(ok, "curve not supported")
+	s.True(c1 == c2, "curve arithmetic is not cached")
+}
+
+func (s *ScalarTestSuite) TestRandomize() {
+	params := s.Curve.Params()
+	c, ok := jacobianFor(s.Curve)
+	s.Require().True(ok, "curve not supported")
+	k, err := GenerateKey(params, rand.Reader)
+	s.Require().NoError(err, "failed to generate scalar")
+	l, err := generateScalar(params.P, rand.Reader)
+	s.Require().NoError(err, "failed to generate z coordinate")
+
+	wantX, wantY := s.Curve.ScalarMult(params.Gx, params.Gy, k)
+	x, y := c.scalarMult(params.Gx, params.Gy, k, l)
+	s.Equal(wantX.Text(16), x.Text(16), "x is not equal")
+	s.Equal(wantY.Text(16), y.Text(16), "y is not equal")
+}
+
+func TestScalarP224(t *testing.T) {
+	suite.Run(t, &ScalarTestSuite{Curve: elliptic.P224()})
+}
+
+func TestScalarP256(t *testing.T) {
+	suite.Run(t, &ScalarTestSuite{Curve: elliptic.P256()})
+}
+
+func TestScalarP384(t *testing.T) {
+	suite.Run(t, &ScalarTestSuite{Curve: elliptic.P384()})
+}
+
+func TestScalarP521(t *testing.T) {
+	suite.Run(t, &ScalarTestSuite{Curve: elliptic.P521()})
+}
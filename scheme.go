@@ -0,0 +1,238 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// SchemeConfig contains the parameters shared by all key-agreement schemes.
+type SchemeConfig struct {
+	// KDF is the key-derivation function that derives the keying material
+	// from the shared secret.
+	KDF KDF
+
+	// KeyLen is the length of the derived keying material in bytes.
+	KeyLen int
+
+	// AlgorithmID indicates how the derived keying material will be used.
+	AlgorithmID []byte
+
+	// SuppPubInfo and SuppPrivInfo are optional public and private data
+	// that is known to both parties and bound to the derived keying
+	// material.
+	SuppPubInfo  []byte
+	SuppPrivInfo []byte
+
+	// Confirmation contains the parameters for key confirmation. If it is
+	// set, the MacKey is derived in front of the KeyLen bytes of keying
+	// material.
+	Confirmation *KeyConfirmation
+
+	// Rand is used to blind the private keys. If Rand is nil, crypto/rand
+	// is used instead.
+	Rand io.Reader
+}
+
+func (c *SchemeConfig) rand() io.Reader {
+	if c.Rand == nil {
+		return rand.Reader
+	}
+	return c.Rand
+}
+
+// agree validates the public keys of the other party and calculates the
+// shared secret with BlindMQV. The static public key is validated fully and
+// the ephemeral public key partially.
+func (c *SchemeConfig) agree(ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error) {
+	if err := validatePeerKeys(otherStatic, otherEphemeral); err != nil {
+		return nil, err
+	}
+	z, err := BlindAgree(ownStatic, ownEphemeral, otherStatic, otherEphemeral, c.rand())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate shared secret: %w", err)
+	}
+	return z, nil
+}
+
+// deriveKey derives the keying material from the shared secret z. The
+// identifiers of party U and party V are used as PartyUInfo and PartyVInfo.
+// If key confirmation is enabled, the MacKey is returned separately.
+func (c *SchemeConfig) deriveKey(z *SharedSecret, idU, idV []byte) ([]byte, []byte, error) {
+	if c.KDF == nil {
+		return nil, nil, errors.New("missing key-derivation function")
+	}
+	length := c.KeyLen
+	macKeyLen := 0
+	if c.Confirmation != nil {
+		if err := c.Confirmation.check(); err != nil {
+			return nil, nil, err
+		}
+		macKeyLen = c.Confirmation.MacKeyLen
+		length += macKeyLen
+	}
+
+	info := &FixedInfo{
+		AlgorithmID:  c.AlgorithmID,
+		PartyUInfo:   idU,
+		PartyVInfo:   idV,
+		SuppPubInfo:  c.SuppPubInfo,
+		SuppPrivInfo: c.SuppPrivInfo,
+	}
+	fixedInfo := info.Bytes()
+	defer WipeBytes(fixedInfo)
+	key, err := c.KDF.DeriveKey(z.Bytes(), fixedInfo, length)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	return key[:macKeyLen], key[macKeyLen:], nil
+}
+
+// confirm derives the keying material and prepares key confirmation for
+// the given role. ephemDataU and ephemDataV are the ephemeral public keys
+// or nonces of the parties.
+func (c *SchemeConfig) confirm(z *SharedSecret, role Role, idU, idV, ephemDataU, ephemDataV []byte) ([]byte, *Confirmation, error) {
+	if c.Confirmation == nil {
+		return nil, nil, errors.New("key confirmation is not configured")
+	}
+	macKey, key, err := c.deriveKey(z, idU, idV)
+	if err != nil {
+		return nil, nil, err
+	}
+	conf := &Confirmation{
+		config: c.Confirmation,
+		role:   role,
+		macKey: macKey,
+		idU:    idU,
+		idV:    idV,
+		ephemU: ephemDataU,
+		ephemV: ephemDataV,
+	}
+	return key, conf, nil
+}
+
+// confirmEphemeral is similar to confirm, but uses the ephemeral public keys
+// of both parties as EphemData.
+func (c *SchemeConfig) confirmEphemeral(z *SharedSecret, role Role, idU, idV []byte, ownEphemeral, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	ephemU, err := MarshalPublicKey(ownEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	ephemV, err := MarshalPublicKey(otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	if role == PartyV {
+		ephemU, ephemV = ephemV, ephemU
+	}
+	return c.confirm(z, role, idU, idV, ephemU, ephemV)
+}
+
+// FullMQV implements the full MQV scheme C(2e, 2s, ECC MQV) where both
+// parties contribute a static and an ephemeral key pair. Party U is the
+// initiator and party V the responder of the key agreement, both parties
+// pass the identifiers in the same order. See section 6.1.1.4 of
+// SP 800-56A Rev. 3 for more details.
+type FullMQV struct {
+	SchemeConfig
+}
+
+// DeriveKey calculates the shared secret with BlindMQV and returns the
+// derived keying material. The static public key of the other party is
+// validated fully and its ephemeral public key partially.
+func (s *FullMQV) DeriveKey(idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agree(ownStatic, ownEphemeral, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// ConfirmKey is similar to DeriveKey, but additionally returns the key
+// confirmation for the party with the given role. The ephemeral public keys
+// are used as EphemData. See section 6.1.1.5 of SP 800-56A Rev. 3 for more
+// details.
+func (s *FullMQV) ConfirmKey(role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agree(ownStatic, ownEphemeral, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+	return s.confirmEphemeral(z, role, idU, idV, ownEphemeral.Public, otherEphemeral)
+}
+
+// OnePassMQV implements the one-pass MQV scheme C(1e, 2s, ECC MQV) where
+// party U (the sender) contributes a static and an ephemeral key pair and
+// party V (the receiver) only a static key pair. See section 6.2.1.4 of
+// SP 800-56A Rev. 3 for more details.
+type OnePassMQV struct {
+	SchemeConfig
+}
+
+// DeriveKeyU returns the derived keying material for party U, which
+// sends its ephemeral public key to party V.
+func (s *OnePassMQV) DeriveKeyU(idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, error) {
+	z, err := s.agree(ownStatic, ownEphemeral, otherStatic, otherStatic)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// DeriveKeyV returns the derived keying material for party V, which
+// receives the ephemeral public key of party U.
+func (s *OnePassMQV) DeriveKeyV(idU, idV []byte, ownStatic *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agree(ownStatic, ownStatic, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// ConfirmKeyU is similar to DeriveKeyU, but additionally returns the key
+// confirmation for party U. Since party V has no ephemeral key, the nonce
+// sent by party V is used as its EphemData. It may be nil if party V does
+// not provide a MacTag. See section 6.2.1.5 of SP 800-56A Rev. 3 for more
+// details.
+func (s *OnePassMQV) ConfirmKeyU(idU, idV, nonceV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agree(ownStatic, ownEphemeral, otherStatic, otherStatic)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(ownEphemeral.Public)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyU, idU, idV, ephemU, nonceV)
+}
+
+// ConfirmKeyV is similar to DeriveKeyV, but additionally returns the key
+// confirmation for party V. See ConfirmKeyU for details.
+func (s *OnePassMQV) ConfirmKeyV(idU, idV, nonceV []byte, ownStatic *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agree(ownStatic, ownStatic, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyV, idU, idV, ephemU, nonceV)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemeTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	config SchemeConfig
+	idU    []byte
+	idV    []byte
+
+	uStatic    *KeyPair
+	uEphemeral *KeyPair
+	vStatic    *KeyPair
+	vEphemeral *KeyPair
+}
+
+func (s *SchemeTestSuite) SetupTest() {
+	s.config = SchemeConfig{
+		KDF:         &OneStepKDF{Hash: sha256.New},
+		KeyLen:      32,
+		AlgorithmID: []byte("AES-256"),
+	}
+	s.idU = []byte("alice")
+	s.idV = []byte("bob")
+	s.uStatic = s.generateKeyPair("u static")
+	s.uEphemeral = s.generateKeyPair("u ephemeral")
+	s.vStatic = s.generateKeyPair("v static")
+	s.vEphemeral = s.generateKeyPair("v ephemeral")
+}
+
+func (s *SchemeTestSuite) generateKeyPair(name string) *KeyPair {
+	kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoErrorf(err, "failed to create key pair %q", name)
+	return kp
+}
+
+func (s *SchemeTestSuite) TestFull() {
+	scheme := &FullMQV{s.config}
+
+	keyU, err := scheme.DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.NoError(err, "failed to derive key for u")
+	s.Len(keyU, s.config.KeyLen, "invalid key length")
+
+	keyV, err := scheme.DeriveKey(s.idU, s.idV, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+	s.NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+
+	keyOther, err := scheme.DeriveKey(s.idV, s.idU, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+	s.NoError(err, "failed to derive key with swapped identifiers")
+	s.NotEqual(keyU, keyOther, "identifiers are not bound to the key")
+}
+
+func (s *SchemeTestSuite) TestOnePass() {
+	scheme := &OnePassMQV{s.config}
+
+	keyU, err := scheme.DeriveKeyU(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public)
+	s.NoError(err, "failed to derive key for u")
+	s.Len(keyU, s.config.KeyLen, "invalid key length")
+
+	keyV, err := scheme.DeriveKeyV(s.idU, s.idV, s.vStatic, s.uStatic.Public, s.uEphemeral.Public)
+	s.NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+}
+
+func (s *SchemeTestSuite) TestKDFs() {
+	kdfs := []KDF{
+		&OneStepKDF{Hash: sha256.New},
+		&OneStepHMACKDF{Hash: sha256.New, Salt: []byte("salt")},
+		&TwoStepKDF{Hash: sha256.New},
+	}
+	for _, kdf := range kdfs {
+		config := s.config
+		config.KDF = kdf
+		config.SuppPubInfo = []byte{0, 0, 1, 0}
+		config.SuppPrivInfo = []byte("secret")
+		scheme := &FullMQV{config}
+
+		keyU, err := scheme.DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+		s.NoErrorf(err, "failed to derive key for u with %T", kdf)
+
+		keyV, err := scheme.DeriveKey(s.idU, s.idV, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+		s.NoErrorf(err, "failed to derive key for v with %T", kdf)
+		s.Equalf(keyU, keyV, "keys are not equal with %T", kdf)
+	}
+}
+
+func (s *SchemeTestSuite) TestMissingKDF() {
+	scheme := &FullMQV{SchemeConfig{KeyLen: 32}}
+	_, err := scheme.DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Error(err, "missing kdf accepted")
+}
+
+func TestSchemeP224(t *testing.T) {
+	suite.Run(t, &SchemeTestSuite{Curve: elliptic.P224()})
+}
+
+func TestSchemeP256(t *testing.T) {
+	suite.Run(t, &SchemeTestSuite{Curve: elliptic.P256()})
+}
+
+func TestSchemeP384(t *testing.T) {
+	suite.Run(t, &SchemeTestSuite{Curve: elliptic.P384()})
+}
+
+func TestSchemeP521(t *testing.T) {
+	suite.Run(t, &SchemeTestSuite{Curve: elliptic.P521()})
+}
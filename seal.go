@@ -0,0 +1,269 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// sealVersion is the version of the envelope produced by Seal.
+const sealVersion = 1
+
+// Cipher identifies the AEAD that encrypts the payload of an envelope.
+type Cipher byte
+
+const (
+	// AES256GCM is AES with a 256-bit key in Galois/Counter Mode.
+	AES256GCM Cipher = 1
+
+	// ChaCha20Poly1305 is the AEAD of RFC 8439.
+	ChaCha20Poly1305 Cipher = 2
+)
+
+// keySize returns the key length of the AEAD in bytes.
+func (c Cipher) keySize() (int, error) {
+	switch c {
+	case AES256GCM:
+		return 32, nil
+	case ChaCha20Poly1305:
+		return chacha20poly1305.KeySize, nil
+	}
+	return 0, fmt.Errorf("%w: unsupported cipher %d", ErrInvalidMessage, c)
+}
+
+// aead creates the AEAD with the given key.
+func (c Cipher) aead(key []byte) (cipher.AEAD, error) {
+	switch c {
+	case AES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case ChaCha20Poly1305:
+		return chacha20poly1305.New(key)
+	}
+	return nil, fmt.Errorf("%w: unsupported cipher %d", ErrInvalidMessage, c)
+}
+
+// aeadNonceSize and aeadOverhead are the nonce and tag lengths of all
+// supported ciphers.
+const (
+	aeadNonceSize = 12
+	aeadOverhead  = 16
+)
+
+// SealConfig contains the optional parameters of Seal and Open. A nil config
+// is the same as the zero value.
+type SealConfig struct {
+	// Cipher is the AEAD used by Seal. If Cipher is zero, AES256GCM is
+	// used. Open always uses the cipher of the envelope.
+	Cipher Cipher
+
+	// Rand is used to generate the ephemeral key and to blind the private
+	// keys. If Rand is nil, crypto/rand is used instead.
+	Rand io.Reader
+}
+
+func (c *SealConfig) cipher() Cipher {
+	if c == nil || c.Cipher == 0 {
+		return AES256GCM
+	}
+	return c.Cipher
+}
+
+func (c *SealConfig) rand() io.Reader {
+	if c == nil || c.Rand == nil {
+		return rand.Reader
+	}
+	return c.Rand
+}
+
+// Seal encrypts and authenticates the plaintext for the static public key of
+// the recipient with the one-pass MQV key agreement C(1e, 2s), see
+// OnePassInitiate. The AEAD key and nonce are derived from the shared secret
+// with the one-step KDF (SHA-256), which binds them to the static public keys
+// of both parties and to the header of the envelope. The additional data aad
+// is authenticated, but not included in the envelope.
+//
+// The envelope has the following format:
+//
+//	version (1 byte) || cipher (1 byte) || curve OID (DER) ||
+//	ephemeral public key (uncompressed point) || ciphertext
+//
+// The curve must be registered with an OID.
+func Seal(senderStatic *KeyPair, recipientStatic *PublicKey, plaintext, aad []byte, config *SealConfig) ([]byte, error) {
+	if senderStatic == nil || senderStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	c := config.cipher()
+	if _, err := c.keySize(); err != nil {
+		return nil, err
+	}
+	oid, err := marshalCurveOID(senderStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, z, err := OnePassInitiate(senderStatic, recipientStatic, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	point, err := MarshalPublicKey(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 2+len(oid)+len(point))
+	header = append(header, sealVersion, byte(c))
+	header = append(header, oid...)
+	header = append(header, point...)
+
+	aead, nonce, err := deriveAEAD(z, c, "mqv-seal", senderStatic.Public, recipientStatic, header, aeadNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(header, nonce, plaintext, sealAD(header, aad)), nil
+}
+
+// Open decrypts and authenticates an envelope created by Seal with the own
+// static key pair of the recipient and the static public key of the sender,
+// see OnePassRespond. The additional data aad must be the same as for Seal.
+// Only the Rand field of the config is used.
+func Open(recipientStatic *KeyPair, senderStatic *PublicKey, envelope, aad []byte, config *SealConfig) ([]byte, error) {
+	if recipientStatic == nil || recipientStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	if len(envelope) < 2 {
+		return nil, fmt.Errorf("%w: envelope too short", ErrInvalidMessage)
+	}
+	if envelope[0] != sealVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidMessage, envelope[0])
+	}
+	c := Cipher(envelope[1])
+	if _, err := c.keySize(); err != nil {
+		return nil, err
+	}
+
+	ci, rest, err := unmarshalCurveOID(envelope[2:], recipientStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+	pointLen := 1 + 2*ci.FieldBytes()
+	if len(rest) < pointLen {
+		return nil, fmt.Errorf("%w: envelope too short", ErrInvalidMessage)
+	}
+	ephemeral, err := UnmarshalPublicKey(ci.Curve, rest[:pointLen])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ephemeral key: %w", err)
+	}
+	header := envelope[:len(envelope)-len(rest)+pointLen]
+
+	z, err := OnePassRespond(recipientStatic, senderStatic, ephemeral, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	aead, nonce, err := deriveAEAD(z, c, "mqv-seal", senderStatic, recipientStatic.Public, header, aeadNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, rest[pointLen:], sealAD(header, aad))
+	if err != nil {
+		return nil, ErrAuthentication
+	}
+	return plaintext, nil
+}
+
+// marshalCurveOID returns the DER encoded OID of the curve of the public key.
+func marshalCurveOID(pub *PublicKey) ([]byte, error) {
+	ci, err := LookupCurve(pub.Curve)
+	if err != nil {
+		return nil, err
+	}
+	if len(ci.OID) == 0 {
+		return nil, fmt.Errorf("%w %q: missing oid", ErrUnsupportedCurve, ci.Name)
+	}
+	oid, err := asn1.Marshal(ci.OID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal curve oid: %w", err)
+	}
+	return oid, nil
+}
+
+// unmarshalCurveOID decodes the DER encoded OID at the start of data and
+// returns the curve and the remaining data. The curve must be the curve of
+// the own public key.
+func unmarshalCurveOID(data []byte, own *PublicKey) (*CurveInfo, []byte, error) {
+	var oid asn1.ObjectIdentifier
+	rest, err := asn1.Unmarshal(data, &oid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid curve oid", ErrInvalidMessage)
+	}
+	ci, err := CurveByOID(oid)
+	if err != nil {
+		return nil, nil, err
+	}
+	ownCI, err := LookupCurve(own.Curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ownCI != ci {
+		return nil, nil, fmt.Errorf("%w: message for curve %s", ErrInvalidMessage, ci.Name)
+	}
+	return ci, rest, nil
+}
+
+// deriveAEAD derives the AEAD key and nonceSize bytes of nonce from the
+// shared secret z. The label and the cipher are used as AlgorithmID, the
+// static public keys of the sender and the recipient as PartyUInfo and
+// PartyVInfo and the header of the message as SuppPubInfo.
+func deriveAEAD(z *SharedSecret, c Cipher, label string, sender, recipient *PublicKey, header []byte, nonceSize int) (cipher.AEAD, []byte, error) {
+	idU, err := MarshalPublicKey(sender)
+	if err != nil {
+		return nil, nil, err
+	}
+	idV, err := MarshalPublicKey(recipient)
+	if err != nil {
+		return nil, nil, err
+	}
+	keySize, err := c.keySize()
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &SchemeConfig{
+		KDF:         &OneStepKDF{Hash: sha256.New},
+		KeyLen:      keySize + nonceSize,
+		AlgorithmID: append([]byte(label), byte(c)),
+		SuppPubInfo: header,
+	}
+	_, key, err := config.deriveKey(z, idU, idV)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer WipeBytes(key[:keySize])
+	aead, err := c.aead(key[:keySize])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, key[keySize:], nil
+}
+
+// sealAD returns the additional data of the AEAD, which authenticates the
+// header together with the additional data of the caller. The header is
+// self-delimiting.
+func sealAD(header, aad []byte) []byte {
+	ad := make([]byte, 0, len(header)+len(aad))
+	ad = append(ad, header...)
+	return append(ad, aad...)
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SealTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	sender, recipient *KeyPair
+}
+
+func (s *SealTestSuite) SetupTest() {
+	var err error
+	s.sender, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the sender")
+	s.recipient, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the recipient")
+}
+
+func (s *SealTestSuite) TestSealOpen() {
+	plaintext := []byte("attack at dawn")
+	aad := []byte("header")
+
+	for _, c := range []Cipher{AES256GCM, ChaCha20Poly1305} {
+		envelope, err := Seal(s.sender, s.recipient.Public, plaintext, aad, &SealConfig{Cipher: c})
+		s.Require().NoErrorf(err, "failed to seal with cipher %d", c)
+		s.Equal(byte(c), envelope[1], "wrong cipher in envelope")
+
+		opened, err := Open(s.recipient, s.sender.Public, envelope, aad, nil)
+		s.Require().NoErrorf(err, "failed to open with cipher %d", c)
+		s.Equal(plaintext, opened, "plaintext differs")
+
+		_, err = Open(s.recipient, s.sender.Public, envelope, []byte("other"), nil)
+		s.True(errors.Is(err, ErrAuthentication), "wrong additional data accepted: %v", err)
+	}
+
+	envelope, err := Seal(s.sender, s.recipient.Public, nil, nil, nil)
+	s.Require().NoError(err, "failed to seal empty plaintext")
+	opened, err := Open(s.recipient, s.sender.Public, envelope, nil, nil)
+	s.Require().NoError(err, "failed to open empty plaintext")
+	s.Empty(opened, "plaintext not empty")
+
+	envelope2, err := Seal(s.sender, s.recipient.Public, nil, nil, nil)
+	s.Require().NoError(err, "failed to seal twice")
+	s.NotEqual(envelope, envelope2, "envelope reused")
+}
+
+func (s *SealTestSuite) TestWrongKeys() {
+	envelope, err := Seal(s.sender, s.recipient.Public, []byte("secret"), nil, nil)
+	s.Require().NoError(err, "failed to seal")
+
+	other, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	_, err = Open(other, s.sender.Public, envelope, nil, nil)
+	s.True(errors.Is(err, ErrAuthentication), "wrong recipient accepted: %v", err)
+	_, err = Open(s.recipient, other.Public, envelope, nil, nil)
+	s.True(errors.Is(err, ErrAuthentication), "wrong sender accepted: %v", err)
+
+	if s.Curve != elliptic.P224() {
+		p224, err := GenerateKeyPair(elliptic.P224(), rand.Reader)
+		s.Require().NoError(err, "failed to create key pair")
+		_, err = Open(p224, s.sender.Public, envelope, nil, nil)
+		s.True(errors.Is(err, ErrInvalidMessage), "envelope for other curve accepted: %v", err)
+	}
+}
+
+func (s *SealTestSuite) TestModified() {
+	envelope, err := Seal(s.sender, s.recipient.Public, []byte("secret"), nil, nil)
+	s.Require().NoError(err, "failed to seal")
+
+	for i := range envelope {
+		modified := append([]byte(nil), envelope...)
+		modified[i] ^= 1
+		_, err := Open(s.recipient, s.sender.Public, modified, nil, nil)
+		s.Errorf(err, "modified byte %d accepted", i)
+	}
+	for i := 0; i < len(envelope); i++ {
+		_, err := Open(s.recipient, s.sender.Public, envelope[:i], nil, nil)
+		s.Errorf(err, "truncated envelope of length %d accepted", i)
+	}
+
+	modified := append([]byte(nil), envelope...)
+	modified[0] = 2
+	_, err = Open(s.recipient, s.sender.Public, modified, nil, nil)
+	s.True(errors.Is(err, ErrInvalidMessage), "unknown version accepted: %v", err)
+	modified[0], modified[1] = 1, 0
+	_, err = Open(s.recipient, s.sender.Public, modified, nil, nil)
+	s.True(errors.Is(err, ErrInvalidMessage), "unknown cipher accepted: %v", err)
+}
+
+func TestSealP224(t *testing.T) {
+	suite.Run(t, &SealTestSuite{Curve: elliptic.P224()})
+}
+
+func TestSealP256(t *testing.T) {
+	suite.Run(t, &SealTestSuite{Curve: elliptic.P256()})
+}
+
+func TestSealP384(t *testing.T) {
+	suite.Run(t, &SealTestSuite{Curve: elliptic.P384()})
+}
+
+func TestSealP521(t *testing.T) {
+	suite.Run(t, &SealTestSuite{Curve: elliptic.P521()})
+}
+
+func TestSealBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &SealTestSuite{Curve: BrainpoolP256r1()})
+}
+
+func TestSealUnsupportedCurve(t *testing.T) {
+	curve := testCurveH4()
+	sender, err := GenerateKeyPair(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+	recipient, err := GenerateKeyPair(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to create key pair: %v", err)
+	}
+	if _, err := Seal(sender, recipient.Public, nil, nil, nil); !errors.Is(err, ErrUnsupportedCurve) {
+		t.Errorf("curve without OID accepted: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+)
+
+// SharedSecret holds the shared secret Z of a key agreement. Z is stored as
+// the fixed-length byte string of the x-coordinate of the shared point, so
+// both parties always obtain the same number of bytes.
+type SharedSecret struct {
+	z []byte
+}
+
+// NewSharedSecret returns the shared secret for the x-coordinate of the
+// shared point on the given curve. An error is returned if x is not in the
+// range [0, p-1].
+func NewSharedSecret(x *big.Int, curve elliptic.Curve) (*SharedSecret, error) {
+	z, err := fieldElementBytes(x, curve.Params().P)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedSecret{z: z}, nil
+}
+
+// NewFFCSharedSecret returns the shared secret for the FFC shared secret z
+// of the given group. An error is returned if z is not in the range
+// [0, p-1].
+func NewFFCSharedSecret(z *big.Int, params *FFCParams) (*SharedSecret, error) {
+	b, err := fieldElementBytes(z, params.P)
+	if err != nil {
+		return nil, err
+	}
+	return &SharedSecret{z: b}, nil
+}
+
+// concatSecrets returns the concatenation of the shared secrets, which is
+// used by the unified model schemes (e.g. Z = Ze || Zs).
+func concatSecrets(secrets ...*SharedSecret) *SharedSecret {
+	n := 0
+	for _, s := range secrets {
+		n += len(s.z)
+	}
+	z := make([]byte, 0, n)
+	for _, s := range secrets {
+		z = append(z, s.z...)
+	}
+	return &SharedSecret{z: z}
+}
+
+// Bytes returns Z as a byte string. The returned slice is shared with s and
+// is wiped by Destroy.
+func (s *SharedSecret) Bytes() []byte {
+	return s.z
+}
+
+// Equal reports in constant time whether s and o hold the same secret.
+func (s *SharedSecret) Equal(o *SharedSecret) bool {
+	return subtle.ConstantTimeCompare(s.z, o.z) == 1
+}
+
+// Destroy overrides the shared secret with zeros.
+func (s *SharedSecret) Destroy() {
+	WipeBytes(s.z)
+}
+
+// fieldElementBytes converts the field element x to a byte string whose
+// length is the byte length of the field size p, as described by section
+// 5.7.1.2 of SP 800-56A Rev. 3 (Field-Element-to-Byte-String conversion).
+func fieldElementBytes(x *big.Int, p *big.Int) ([]byte, error) {
+	if x == nil || x.Sign() < 0 || x.Cmp(p) >= 0 {
+		return nil, errors.New("field element out of range")
+	}
+	r := make([]byte, (p.BitLen()+7)>>3)
+	b := x.Bytes()
+	defer WipeBytes(b)
+	copy(r[len(r)-len(b):], b)
+	return r, nil
+}
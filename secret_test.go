@@ -0,0 +1,72 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type SecretTestSuite struct {
+	suite.Suite
+}
+
+func (s *SecretTestSuite) newSecret(x *big.Int, curve elliptic.Curve) *SharedSecret {
+	z, err := NewSharedSecret(x, curve)
+	s.Require().NoErrorf(err, "failed to create shared secret for %s", x.Text(16))
+	return z
+}
+
+func (s *SecretTestSuite) TestFixedLength() {
+	curves := []elliptic.Curve{elliptic.P224(), elliptic.P256(), elliptic.P384(), elliptic.P521()}
+	for _, curve := range curves {
+		size := (curve.Params().BitSize + 7) / 8
+		for _, x := range []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(0x1234)} {
+			z := s.newSecret(x, curve)
+			s.Lenf(z.Bytes(), size, "invalid length for %s", curve.Params().Name)
+			s.Equal(x.Text(16), new(big.Int).SetBytes(z.Bytes()).Text(16), "invalid value")
+		}
+
+		// x with a leading zero byte
+		x := new(big.Int).Rsh(curve.Params().P, 8)
+		z := s.newSecret(x, curve)
+		s.Lenf(z.Bytes(), size, "invalid length for %s", curve.Params().Name)
+		s.Equal(byte(0), z.Bytes()[0], "missing leading zero")
+	}
+}
+
+func (s *SecretTestSuite) TestEqual() {
+	curve := elliptic.P256()
+	a := s.newSecret(big.NewInt(42), curve)
+	b := s.newSecret(big.NewInt(42), curve)
+	c := s.newSecret(big.NewInt(43), curve)
+
+	s.True(a.Equal(b), "equal secrets differ")
+	s.False(a.Equal(c), "different secrets are equal")
+	s.False(a.Equal(s.newSecret(big.NewInt(42), elliptic.P384())), "secrets of different length are equal")
+}
+
+func (s *SecretTestSuite) TestDestroy() {
+	z := s.newSecret(big.NewInt(0x1234), elliptic.P256())
+	b := z.Bytes()
+	z.Destroy()
+	s.Equal(make([]byte, len(b)), b, "secret not wiped")
+}
+
+func (s *SecretTestSuite) TestOutOfRange() {
+	curve := elliptic.P256()
+	for _, x := range []*big.Int{big.NewInt(-1), curve.Params().P, new(big.Int).Lsh(one, 300)} {
+		_, err := NewSharedSecret(x, curve)
+		s.Errorf(err, "field element %s accepted", x.Text(16))
+	}
+	_, err := NewFFCSharedSecret(new(big.Int).Lsh(one, 4096), FFDHE2048())
+	s.Error(err, "oversized ffc shared secret accepted")
+}
+
+func TestSecret(t *testing.T) {
+	suite.Run(t, new(SecretTestSuite))
+}
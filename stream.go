@@ -0,0 +1,446 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// streamVersion is the version of the header written by StreamWriter.
+	streamVersion = 1
+
+	// StreamChunkSize is the length of the plaintext of all chunks but the
+	// last one.
+	StreamChunkSize = 64 << 10
+
+	// streamNoncePrefixSize is the length of the nonce prefix derived from
+	// the shared secret. It is followed by a 32-bit counter and the flag of
+	// the last chunk.
+	streamNoncePrefixSize = aeadNonceSize - 5
+
+	// streamMaxChunks is the maximal number of chunks of a stream.
+	streamMaxChunks = math.MaxUint32 + 1
+)
+
+var errStreamClosed = errors.New("stream is closed")
+var errNotSeekable = errors.New("stream is not seekable")
+
+// setStreamNonce sets the counter and the flag of the last chunk of the
+// nonce, as described by the STREAM construction of Hoang, Reyhanitabar,
+// Rogaway and Vizár.
+func setStreamNonce(nonce []byte, counter uint64, last bool) {
+	binary.BigEndian.PutUint32(nonce[streamNoncePrefixSize:], uint32(counter))
+	nonce[aeadNonceSize-1] = 0
+	if last {
+		nonce[aeadNonceSize-1] = 1
+	}
+}
+
+// StreamWriter encrypts a stream of data for the static public key of the
+// recipient. The plaintext is split into chunks of StreamChunkSize bytes,
+// which are encrypted and authenticated separately with the STREAM
+// construction. The last chunk is marked so that truncation of the stream
+// is detected. Close must be called to write the last chunk.
+//
+// The stream starts with the following header:
+//
+//	version (1 byte) || cipher (1 byte) || curve OID (DER) ||
+//	key ID of the sender (KeyIDSize bytes) ||
+//	ephemeral public key (uncompressed point)
+type StreamWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	nonce   [aeadNonceSize]byte
+	counter uint64
+	buf     []byte
+	err     error
+}
+
+// NewStreamWriter writes the header of the stream to dst and returns a
+// StreamWriter that encrypts the data for the static public key of the
+// recipient. The key is agreed with the one-pass MQV key agreement
+// C(1e, 2s), see OnePassInitiate, and derived like for Seal.
+func NewStreamWriter(dst io.Writer, senderStatic *KeyPair, recipientStatic *PublicKey, config *SealConfig) (*StreamWriter, error) {
+	if senderStatic == nil || senderStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	c := config.cipher()
+	if _, err := c.keySize(); err != nil {
+		return nil, err
+	}
+	oid, err := marshalCurveOID(senderStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := KeyID(senderStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+
+	ephemeral, z, err := OnePassInitiate(senderStatic, recipientStatic, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	point, err := MarshalPublicKey(ephemeral)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, 2+len(oid)+len(keyID)+len(point))
+	header = append(header, streamVersion, byte(c))
+	header = append(header, oid...)
+	header = append(header, keyID...)
+	header = append(header, point...)
+
+	aead, prefix, err := deriveAEAD(z, c, "mqv-stream", senderStatic.Public, recipientStatic, header, streamNoncePrefixSize)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	w := &StreamWriter{
+		dst:  dst,
+		aead: aead,
+		buf:  make([]byte, 0, StreamChunkSize+aead.Overhead()),
+	}
+	copy(w.nonce[:], prefix)
+	return w, nil
+}
+
+// Write encrypts p. The data is buffered until a chunk is complete.
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := 0
+	for len(p) > 0 {
+		// a full chunk is only written once more data follows, because
+		// the last chunk may be full as well
+		if len(w.buf) == StreamChunkSize {
+			if err := w.flush(false); err != nil {
+				return n, err
+			}
+		}
+		k := copy(w.buf[len(w.buf):StreamChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+		n += k
+	}
+	return n, nil
+}
+
+// Close writes the last chunk. It does not close the underlying writer.
+func (w *StreamWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flush(true); err != nil {
+		return err
+	}
+	w.err = errStreamClosed
+	return nil
+}
+
+// flush encrypts the buffered chunk and writes it to the underlying writer.
+func (w *StreamWriter) flush(last bool) error {
+	if w.counter >= streamMaxChunks {
+		w.err = errors.New("stream too long")
+		return w.err
+	}
+	setStreamNonce(w.nonce[:], w.counter, last)
+	ct := w.aead.Seal(w.buf[:0], w.nonce[:], w.buf, nil)
+	w.counter++
+	w.buf = w.buf[:0]
+	if _, err := w.dst.Write(ct); err != nil {
+		w.err = fmt.Errorf("failed to write chunk: %w", err)
+		return w.err
+	}
+	return nil
+}
+
+// StreamReader decrypts a stream written by StreamWriter. Every chunk is
+// authenticated before its plaintext is returned and the end of the stream
+// is only reported after the last chunk has been authenticated. If the
+// underlying reader implements io.Seeker, the StreamReader can seek to any
+// position of the plaintext and only decrypts the chunks that are read.
+type StreamReader struct {
+	src       io.Reader
+	sender    *PublicKey
+	aead      cipher.AEAD
+	nonce     [aeadNonceSize]byte
+	base      int64
+	headerLen int64
+
+	buf      []byte
+	plain    []byte
+	chunk    []byte
+	chunkIdx uint64
+	loaded   bool
+	next     uint64
+	pos      int64
+	size     int64
+	err      error
+}
+
+// NewStreamReader reads the header of the stream from src and returns a
+// StreamReader that decrypts the data with the own static key pair of the
+// recipient. The static public key of the sender is selected from senders by
+// the key ID of the header. If none of the keys matches, ErrUnknownKey is
+// returned. Only the Rand field of the config is used.
+func NewStreamReader(src io.Reader, recipientStatic *KeyPair, senders []*PublicKey, config *SealConfig) (*StreamReader, error) {
+	if recipientStatic == nil || recipientStatic.Public == nil {
+		return nil, fmt.Errorf("%w: missing key pair", ErrInvalidPrivateKey)
+	}
+	r := &StreamReader{src: src, size: -1}
+	if s, ok := src.(io.Seeker); ok {
+		base, err := s.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to seek: %w", err)
+		}
+		r.base = base
+	}
+
+	// version, cipher and the tag and length of the curve OID
+	header := make([]byte, 4)
+	if err := readHeader(src, header); err != nil {
+		return nil, err
+	}
+	if header[0] != streamVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidMessage, header[0])
+	}
+	c := Cipher(header[1])
+	if _, err := c.keySize(); err != nil {
+		return nil, err
+	}
+	if header[2] != 0x06 || header[3] > 0x7f {
+		return nil, fmt.Errorf("%w: invalid curve oid", ErrInvalidMessage)
+	}
+	header = append(header, make([]byte, header[3])...)
+	if err := readHeader(src, header[4:]); err != nil {
+		return nil, err
+	}
+	ci, _, err := unmarshalCurveOID(header[2:], recipientStatic.Public)
+	if err != nil {
+		return nil, err
+	}
+	offset := len(header)
+	header = append(header, make([]byte, KeyIDSize+1+2*ci.FieldBytes())...)
+	if err := readHeader(src, header[offset:]); err != nil {
+		return nil, err
+	}
+	r.headerLen = int64(len(header))
+
+	r.sender, err = findKey(header[offset:offset+KeyIDSize], senders)
+	if err != nil {
+		return nil, err
+	}
+	ephemeral, err := UnmarshalPublicKey(ci.Curve, header[offset+KeyIDSize:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ephemeral key: %w", err)
+	}
+	z, err := OnePassRespond(recipientStatic, r.sender, ephemeral, config.rand())
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	aead, prefix, err := deriveAEAD(z, c, "mqv-stream", r.sender, recipientStatic.Public, header, streamNoncePrefixSize)
+	if err != nil {
+		return nil, err
+	}
+	r.aead = aead
+	copy(r.nonce[:], prefix)
+	r.buf = make([]byte, StreamChunkSize+aead.Overhead())
+	r.plain = make([]byte, 0, StreamChunkSize)
+	return r, nil
+}
+
+// readHeader reads len(buf) bytes of the header.
+func readHeader(src io.Reader, buf []byte) error {
+	if _, err := io.ReadFull(src, buf); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return fmt.Errorf("%w: truncated header", ErrInvalidMessage)
+		}
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	return nil
+}
+
+// findKey returns the public key with the given key ID.
+func findKey(keyID []byte, keys []*PublicKey) (*PublicKey, error) {
+	for _, key := range keys {
+		id, err := KeyID(key)
+		if err == nil && bytes.Equal(id, keyID) {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %x", ErrUnknownKey, keyID)
+}
+
+// Sender returns the static public key of the sender.
+func (r *StreamReader) Sender() *PublicKey {
+	return r.sender
+}
+
+// Read decrypts the next bytes of the stream. It returns io.EOF only after
+// the last chunk has been authenticated.
+func (r *StreamReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if r.size >= 0 && r.pos >= r.size {
+		return 0, io.EOF
+	}
+	idx := uint64(r.pos / StreamChunkSize)
+	if !r.loaded || r.chunkIdx != idx {
+		if err := r.loadChunk(idx); err != nil {
+			r.err = err
+			return 0, err
+		}
+		if r.size >= 0 && r.pos >= r.size {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.chunk[r.pos%StreamChunkSize:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+// Seek sets the position of the next Read in the plaintext. The underlying
+// reader must implement io.Seeker. The first call authenticates the last
+// chunk, see Size.
+func (r *StreamReader) Seek(offset int64, whence int) (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if _, ok := r.src.(io.Seeker); !ok {
+		return 0, errNotSeekable
+	}
+	// the size is required to report the end of the stream at any position
+	size, err := r.Size()
+	if err != nil {
+		return 0, err
+	}
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = size + offset
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+// Size returns the length of the plaintext. The underlying reader must
+// implement io.Seeker, unless the last chunk has already been read. The last
+// chunk is authenticated to detect truncation of the stream.
+func (r *StreamReader) Size() (int64, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	if r.size >= 0 {
+		return r.size, nil
+	}
+	s, ok := r.src.(io.Seeker)
+	if !ok {
+		return 0, errNotSeekable
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, fmt.Errorf("failed to seek: %w", err)
+	}
+	r.next = math.MaxUint64
+	ctLen := end - r.base - r.headerLen
+	if ctLen <= 0 {
+		r.err = fmt.Errorf("%w: truncated stream", ErrInvalidMessage)
+		return 0, r.err
+	}
+	if err := r.loadChunk(uint64((ctLen - 1) / int64(len(r.buf)))); err != nil {
+		r.err = err
+		return 0, err
+	}
+	if r.size < 0 {
+		r.err = fmt.Errorf("%w: truncated stream", ErrInvalidMessage)
+		return 0, r.err
+	}
+	return r.size, nil
+}
+
+// loadChunk reads and decrypts the chunk idx. A full chunk is decrypted as
+// the last chunk only if decrypting it as an inner chunk fails.
+func (r *StreamReader) loadChunk(idx uint64) error {
+	if idx >= streamMaxChunks {
+		return fmt.Errorf("%w: stream too long", ErrInvalidMessage)
+	}
+	r.loaded = false
+	if idx != r.next {
+		s, ok := r.src.(io.Seeker)
+		if !ok {
+			return errNotSeekable
+		}
+		if _, err := s.Seek(r.base+r.headerLen+int64(idx)*int64(len(r.buf)), io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+	}
+
+	n, err := io.ReadFull(r.src, r.buf)
+	r.next = idx + 1
+	switch {
+	case err == io.EOF:
+		return fmt.Errorf("%w: truncated stream", ErrInvalidMessage)
+	case err != nil && err != io.ErrUnexpectedEOF:
+		return fmt.Errorf("failed to read chunk: %w", err)
+	}
+	ct := r.buf[:n]
+
+	last := n < len(r.buf)
+	chunk, err := r.open(ct, idx, last)
+	if err != nil && !last {
+		last = true
+		chunk, err = r.open(ct, idx, last)
+		if err == nil {
+			var trailing [1]byte
+			if k, _ := io.ReadFull(r.src, trailing[:]); k > 0 {
+				return fmt.Errorf("%w: trailing data after last chunk", ErrInvalidMessage)
+			}
+		}
+	}
+	if err != nil {
+		return ErrAuthentication
+	}
+	if last && len(chunk) == 0 && idx > 0 {
+		return fmt.Errorf("%w: empty last chunk", ErrInvalidMessage)
+	}
+
+	r.chunk, r.chunkIdx, r.loaded = chunk, idx, true
+	if last {
+		r.size = int64(idx)*StreamChunkSize + int64(len(chunk))
+	}
+	return nil
+}
+
+// open decrypts the chunk idx.
+func (r *StreamReader) open(ct []byte, idx uint64, last bool) ([]byte, error) {
+	setStreamNonce(r.nonce[:], idx, last)
+	return r.aead.Open(r.plain[:0], r.nonce[:], ct, nil)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"bytes"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type StreamTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	sender, recipient *KeyPair
+}
+
+func (s *StreamTestSuite) SetupTest() {
+	var err error
+	s.sender, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the sender")
+	s.recipient, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair of the recipient")
+}
+
+// encrypt encrypts the plaintext with writes of different lengths.
+func (s *StreamTestSuite) encrypt(plaintext []byte, config *SealConfig) []byte {
+	var buf bytes.Buffer
+	w, err := NewStreamWriter(&buf, s.sender, s.recipient.Public, config)
+	s.Require().NoError(err, "failed to create stream writer")
+	for i, p := 1, plaintext; len(p) > 0; i *= 3 {
+		n := i
+		if n > len(p) {
+			n = len(p)
+		}
+		k, err := w.Write(p[:n])
+		s.Require().NoError(err, "failed to write")
+		s.Require().Equal(n, k, "short write")
+		p = p[n:]
+	}
+	s.Require().NoError(w.Close(), "failed to close stream writer")
+	_, err = w.Write([]byte{0})
+	s.Error(err, "write after close accepted")
+	return buf.Bytes()
+}
+
+// decrypt decrypts the stream without seeking.
+func (s *StreamTestSuite) decrypt(stream []byte) ([]byte, error) {
+	r, err := NewStreamReader(struct{ io.Reader }{bytes.NewReader(stream)}, s.recipient, []*PublicKey{s.sender.Public}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (s *StreamTestSuite) TestEncryptDecrypt() {
+	for _, size := range []int{0, 1, StreamChunkSize - 1, StreamChunkSize, StreamChunkSize + 1, 3 * StreamChunkSize} {
+		plaintext := make([]byte, size)
+		_, err := rand.Read(plaintext)
+		s.Require().NoError(err, "failed to read random data")
+
+		for _, c := range []Cipher{AES256GCM, ChaCha20Poly1305} {
+			stream := s.encrypt(plaintext, &SealConfig{Cipher: c})
+			decrypted, err := s.decrypt(stream)
+			s.Require().NoErrorf(err, "failed to decrypt %d bytes with cipher %d", size, c)
+			s.Equalf(plaintext, decrypted, "plaintext of %d bytes differs with cipher %d", size, c)
+		}
+	}
+}
+
+func (s *StreamTestSuite) TestSeek() {
+	plaintext := make([]byte, 2*StreamChunkSize+100)
+	_, err := rand.Read(plaintext)
+	s.Require().NoError(err, "failed to read random data")
+	stream := append([]byte("prefix"), s.encrypt(plaintext, nil)...)
+
+	src := bytes.NewReader(stream)
+	_, err = src.Seek(6, io.SeekStart)
+	s.Require().NoError(err, "failed to skip prefix")
+	r, err := NewStreamReader(src, s.recipient, []*PublicKey{s.sender.Public}, nil)
+	s.Require().NoError(err, "failed to create stream reader")
+	s.True(r.Sender() == s.sender.Public, "wrong sender")
+
+	size, err := r.Size()
+	s.Require().NoError(err, "failed to get size")
+	s.Equal(int64(len(plaintext)), size, "wrong size")
+
+	for _, pos := range []int64{StreamChunkSize + 10, 5, 2 * StreamChunkSize, StreamChunkSize - 1, 0} {
+		off, err := r.Seek(pos, io.SeekStart)
+		s.Require().NoErrorf(err, "failed to seek to %d", pos)
+		s.Equal(pos, off, "wrong offset")
+		buf := make([]byte, 50)
+		_, err = io.ReadFull(r, buf)
+		s.Require().NoErrorf(err, "failed to read at %d", pos)
+		s.Equalf(plaintext[pos:pos+50], buf, "data at %d differs", pos)
+	}
+
+	off, err := r.Seek(-10, io.SeekEnd)
+	s.Require().NoError(err, "failed to seek relative to the end")
+	s.Equal(size-10, off, "wrong offset")
+	rest, err := ioutil.ReadAll(r)
+	s.Require().NoError(err, "failed to read the end")
+	s.Equal(plaintext[size-10:], rest, "end differs")
+
+	_, err = r.Seek(size+100, io.SeekStart)
+	s.Require().NoError(err, "failed to seek after the end")
+	n, err := r.Read(make([]byte, 1))
+	s.Equal(0, n, "data after the end")
+	s.Equal(io.EOF, err, "missing end of stream")
+	_, err = r.Seek(-1, io.SeekStart)
+	s.Error(err, "negative position accepted")
+
+	nonSeekable, err := NewStreamReader(struct{ io.Reader }{bytes.NewReader(stream[6:])}, s.recipient, []*PublicKey{s.sender.Public}, nil)
+	s.Require().NoError(err, "failed to create stream reader")
+	_, err = nonSeekable.Seek(0, io.SeekStart)
+	s.Error(err, "seek without io.Seeker accepted")
+}
+
+func (s *StreamTestSuite) TestTruncated() {
+	plaintext := make([]byte, 2*StreamChunkSize)
+	stream := s.encrypt(plaintext, nil)
+	headerLen := len(stream) - 2*(StreamChunkSize+16)
+
+	for _, n := range []int{0, headerLen - 1, headerLen, headerLen + StreamChunkSize + 16, len(stream) - 1} {
+		_, err := s.decrypt(stream[:n])
+		s.Errorf(err, "stream truncated to %d bytes accepted", n)
+
+		r, err := NewStreamReader(bytes.NewReader(stream[:n]), s.recipient, []*PublicKey{s.sender.Public}, nil)
+		if err == nil {
+			_, err = r.Size()
+		}
+		s.Errorf(err, "size of stream truncated to %d bytes accepted", n)
+	}
+
+	_, err := s.decrypt(append(stream, 0))
+	s.Error(err, "trailing data accepted")
+
+	// swap the chunks
+	swapped := append([]byte(nil), stream[:headerLen]...)
+	swapped = append(swapped, stream[headerLen+StreamChunkSize+16:]...)
+	swapped = append(swapped, stream[headerLen:headerLen+StreamChunkSize+16]...)
+	_, err = s.decrypt(swapped)
+	s.True(errors.Is(err, ErrAuthentication), "swapped chunks accepted: %v", err)
+}
+
+func (s *StreamTestSuite) TestModified() {
+	stream := s.encrypt([]byte("some data"), nil)
+	for i := range stream {
+		modified := append([]byte(nil), stream...)
+		modified[i] ^= 1
+		_, err := s.decrypt(modified)
+		s.Errorf(err, "modified byte %d accepted", i)
+	}
+}
+
+func (s *StreamTestSuite) TestKeys() {
+	stream := s.encrypt([]byte("some data"), nil)
+
+	other, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+	_, err = NewStreamReader(bytes.NewReader(stream), s.recipient, []*PublicKey{other.Public}, nil)
+	s.True(errors.Is(err, ErrUnknownKey), "unknown sender accepted: %v", err)
+
+	r, err := NewStreamReader(bytes.NewReader(stream), s.recipient, []*PublicKey{other.Public, s.sender.Public}, nil)
+	s.Require().NoError(err, "failed to find sender")
+	s.True(r.Sender() == s.sender.Public, "wrong sender")
+
+	r, err = NewStreamReader(bytes.NewReader(stream), other, []*PublicKey{s.sender.Public}, nil)
+	s.Require().NoError(err, "failed to create stream reader")
+	_, err = ioutil.ReadAll(r)
+	s.True(errors.Is(err, ErrAuthentication), "wrong recipient accepted: %v", err)
+}
+
+func TestStreamP224(t *testing.T) {
+	suite.Run(t, &StreamTestSuite{Curve: elliptic.P224()})
+}
+
+func TestStreamP256(t *testing.T) {
+	suite.Run(t, &StreamTestSuite{Curve: elliptic.P256()})
+}
+
+func TestStreamP384(t *testing.T) {
+	suite.Run(t, &StreamTestSuite{Curve: elliptic.P384()})
+}
+
+func TestStreamP521(t *testing.T) {
+	suite.Run(t, &StreamTestSuite{Curve: elliptic.P521()})
+}
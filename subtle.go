@@ -56,6 +56,157 @@ func (z SubtleInt) AddMod(x, y, n SubtleInt) {
 	z.Select(c1^c2, z, tmp)
 }
 
+// Mul sets z to the product x*y. The length of z must be the sum of the
+// lengths of x and y.
+func (z SubtleInt) Mul(x, y SubtleInt) {
+	if len(z) != len(x)+len(y) {
+		panic("size mismatch")
+	}
+	r := make(SubtleInt, len(z))
+	for i := range x {
+		var c uint
+		for j := range y {
+			hi, lo := bits.Mul(x[i], y[j])
+			lo, c1 := addW(lo, r[i+j], 0)
+			lo, c2 := addW(lo, c, 0)
+			r[i+j] = lo
+			c = hi + c1 + c2
+		}
+		r[i+len(y)] = c
+	}
+	copy(z, r)
+	r.SetZero()
+}
+
+// Mod sets z to x mod n. The length of z must be the length of n, while x
+// can have any length. n must not be zero.
+func (z SubtleInt) Mod(x, n SubtleInt) {
+	if len(z) != len(n) {
+		panic("size mismatch")
+	}
+	r := make(SubtleInt, len(n)+1)
+	defer r.SetZero()
+	tmp := make(SubtleInt, len(n)+1)
+	defer tmp.SetZero()
+	m := make(SubtleInt, len(n)+1)
+	copy(m, n)
+
+	// binary long division, r < n holds after each step
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := bits.UintSize - 1; j >= 0; j-- {
+			r.shiftLeft((x[i] >> uint(j)) & 1)
+			borrow := tmp.Sub(r, m)
+			r.Select(borrow, r, tmp)
+		}
+	}
+	copy(z, r)
+}
+
+// MulMod sets z to x*y mod n. The length of z must be the length of n.
+func (z SubtleInt) MulMod(x, y, n SubtleInt) {
+	prod := make(SubtleInt, len(x)+len(y))
+	defer prod.SetZero()
+	prod.Mul(x, y)
+	z.Mod(prod, n)
+}
+
+// shiftLeft sets z to 2*z+c, with c == 0 or 1, and returns the carry.
+func (z SubtleInt) shiftLeft(c uint) uint {
+	for i := range z {
+		z[i], c = z[i]<<1|c, z[i]>>(bits.UintSize-1)
+	}
+	return c
+}
+
+// SubMod sets z to x-y mod n. Both parameters x and y must be less than n.
+func (z SubtleInt) SubMod(x, y, n SubtleInt) {
+	tmp := make(SubtleInt, len(x))
+	c := z.Sub(x, y)
+	tmp.Add(z, n)
+	z.Select(c, tmp, z)
+}
+
+// ModInverse sets z to the inverse of x modulo the odd modulus n and
+// returns 1 if the inverse exists. Otherwise z is set to zero and 0 is
+// returned. x must be less than n. This function implements a constant
+// time variant of the binary extended Euclidean algorithm with a fixed
+// number of iterations.
+func (z SubtleInt) ModInverse(x, n SubtleInt) uint {
+	if len(x) != len(n) || len(z) != len(n) {
+		panic("size mismatch")
+	}
+	if len(n) == 0 || n[0]&1 == 0 {
+		panic("modulus must be odd")
+	}
+
+	// invariants: a = u*x mod n, b = v*x mod n and b is odd
+	a := append(SubtleInt(nil), x...)
+	defer a.SetZero()
+	b := append(SubtleInt(nil), n...)
+	defer b.SetZero()
+	u := make(SubtleInt, len(n))
+	defer u.SetZero()
+	u[0] = 1
+	v := make(SubtleInt, len(n))
+	defer v.SetZero()
+	tmp := make(SubtleInt, len(n))
+	defer tmp.SetZero()
+
+	// Each iteration reduces the sum of the bit lengths of a and b by at
+	// least one, until a is zero and b is the gcd.
+	for i := 0; i < 2*len(n)*bits.UintSize; i++ {
+		odd := a[0] & 1
+
+		// if a is odd and a < b: swap a and b, as well as u and v
+		swap := odd & a.Less(b)
+		a.swap(swap, b)
+		u.swap(swap, v)
+
+		// if a is odd: a = a - b, u = u - v
+		tmp.Sub(a, b)
+		a.Select(odd, tmp, a)
+		tmp.SubMod(u, v, n)
+		u.Select(odd, tmp, u)
+
+		// a is even: a = a / 2, u = u / 2 mod n
+		a.shiftRight(0)
+		c := tmp.Add(u, n)
+		uOdd := u[0] & 1
+		u.Select(uOdd, tmp, u)
+		u.shiftRight(uOdd & c)
+	}
+
+	// the inverse exists if gcd(x, n) = b = 1
+	diff := b[0] ^ 1
+	for i := 1; i < len(b); i++ {
+		diff |= b[i]
+	}
+	ok := equalW(diff, 0)
+	z.SetZero()
+	z.Select(ok, v, z)
+	return ok
+}
+
+// shiftRight sets z to z/2 + c*2^(W*len(z)-1), with c == 0 or 1.
+func (z SubtleInt) shiftRight(c uint) {
+	for i := len(z) - 1; i >= 0; i-- {
+		z[i], c = z[i]>>1|c<<(bits.UintSize-1), z[i]&1
+	}
+}
+
+// swap exchanges the values of z and x if p = 1 and does nothing if p = 0.
+func (z SubtleInt) swap(p uint, x SubtleInt) {
+	if len(x) != len(z) {
+		panic("size mismatch")
+	}
+	mask := -p
+	for i := range z {
+		t := (z[i] ^ x[i]) & mask
+		z[i] ^= t
+		x[i] ^= t
+	}
+}
+
 // Select sets z to x if p = 1 and y if p = 0.
 func (z SubtleInt) Select(p uint, x, y SubtleInt) {
 	if len(x) != len(y) || len(x) != len(z) {
@@ -105,6 +256,19 @@ func (z SubtleInt) SetBytes(buf []byte) {
 	}
 }
 
+// setValue sets z to the value of the big-endian byte slice buf. Unlike
+// SetBytes, buf is aligned to the least significant end of z.
+func (z SubtleInt) setValue(buf []byte) {
+	const wordSize = bits.UintSize / 8
+	if len(buf) > len(z)*wordSize {
+		panic("size mismatch")
+	}
+	tmp := make([]byte, len(z)*wordSize)
+	defer WipeBytes(tmp)
+	copy(tmp[len(tmp)-len(buf):], buf)
+	z.SetBytes(tmp)
+}
+
 // Bytes returns the value of z as a big-endian byte slice.
 func (z SubtleInt) Bytes() []byte {
 	const sizeBytes = bits.UintSize / 8
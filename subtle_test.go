@@ -131,6 +131,151 @@ func (t *TestSubtleIntSuite) TestAddMod() {
 		}
 	}
 }
+
+func (t *TestSubtleIntSuite) TestMul() {
+	for _, a := range t.testValues2 {
+		for _, b := range t.testValues2 {
+			r := make(SubtleInt, 4)
+			r.Mul(a, b)
+
+			want := new(big.Int).Mul(a.Big(), b.Big())
+			t.Equalf(fmtHex(want), fmtHex(r.Big()), "mul(%v, %v)", a, b)
+
+			r3 := make(SubtleInt, 3)
+			r3.Mul(a[:1], b)
+			want = new(big.Int).Mul(a[:1].Big(), b.Big())
+			t.Equalf(fmtHex(want), fmtHex(r3.Big()), "mul(%v, %v)", a[:1], b)
+
+			t.Panics(func() { r.Mul(a[:1], b) }, "must not multiply into integers with wrong length")
+			t.Panics(func() { r3.Mul(a, b) }, "must not multiply into integers with wrong length")
+		}
+	}
+}
+
+func (t *TestSubtleIntSuite) TestMod() {
+	for _, n := range t.testValues2 {
+		bigN := n.Big()
+		if bigN.Sign() == 0 {
+			continue
+		}
+		for _, a := range t.testValues2 {
+			for _, b := range t.testValues2 {
+				x := SubtleInt{a[0], a[1], b[0], b[1]}
+				r := make(SubtleInt, 2)
+				r.Mod(x, n)
+
+				want := new(big.Int).Mod(x.Big(), bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "mod(%v, %v)", x, n)
+			}
+		}
+
+		r := make(SubtleInt, 2)
+		t.Panics(func() { r.Mod(n, n[:1]) }, "must not reduce into integers with wrong length")
+	}
+}
+
+func (t *TestSubtleIntSuite) TestMulMod() {
+	for _, a := range t.testValues2 {
+		for _, b := range t.testValues2 {
+			for _, n := range t.testValues2 {
+				bigN := n.Big()
+				if bigN.Sign() == 0 {
+					continue
+				}
+
+				r := make(SubtleInt, 2)
+				r.MulMod(a, b, n)
+
+				want := new(big.Int).Mul(a.Big(), b.Big())
+				want.Mod(want, bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "mulMod(%v, %v, %v)", a, b, n)
+			}
+		}
+	}
+}
+
+func (t *TestSubtleIntSuite) TestSubMod() {
+	for _, a := range t.testValues2 {
+		bigA := a.Big()
+		for _, b := range t.testValues2 {
+			bigB := b.Big()
+			for _, n := range t.testValues2 {
+				bigN := n.Big()
+				if bigN.Sign() == 0 || bigA.Cmp(bigN) >= 0 || bigB.Cmp(bigN) >= 0 {
+					continue
+				}
+
+				r := make(SubtleInt, 2)
+				r.SubMod(a, b, n)
+
+				want := new(big.Int).Sub(bigA, bigB)
+				want.Mod(want, bigN)
+				t.Equalf(fmtHex(want), fmtHex(r.Big()), "subMod(%v, %v, %v)", a, b, n)
+			}
+		}
+	}
+}
+
+func (t *TestSubtleIntSuite) TestModInverse() {
+	// all values for small moduli
+	for n := uint(1); n < 512; n += 2 {
+		bigN := new(big.Int).SetUint64(uint64(n))
+		for x := uint(0); x < n; x++ {
+			r := SubtleInt{^uint(0)}
+			ok := r.ModInverse(SubtleInt{x}, SubtleInt{n})
+
+			want := new(big.Int).ModInverse(new(big.Int).SetUint64(uint64(x)), bigN)
+			if want == nil {
+				if ok != 0 || r[0] != 0 {
+					t.Failf("inverse must not exist", "modInverse(%d, %d) = %d, %d", x, n, r[0], ok)
+				}
+				continue
+			}
+			if ok != 1 || r[0] != uint(want.Uint64()) {
+				t.Failf("wrong inverse", "modInverse(%d, %d) = %d, %d, want %v", x, n, r[0], ok, want)
+			}
+		}
+	}
+
+	// interesting values of size 2
+	for _, n := range t.testValues2 {
+		bigN := n.Big()
+		if n[0]&1 == 0 || bigN.Cmp(big.NewInt(1)) <= 0 {
+			continue
+		}
+		for _, x := range t.testValues2 {
+			bigX := x.Big()
+			if bigX.Cmp(bigN) >= 0 {
+				continue
+			}
+
+			r := make(SubtleInt, 2)
+			ok := r.ModInverse(x, n)
+
+			want := new(big.Int).ModInverse(bigX, bigN)
+			if want == nil {
+				t.Equalf(uint(0), ok, "modInverse(%v, %v) must not exist", x, n)
+				t.Equalf(fmtHex(new(big.Int)), fmtHex(r.Big()), "modInverse(%v, %v) must be zero", x, n)
+				continue
+			}
+			t.Equalf(uint(1), ok, "modInverse(%v, %v) must exist", x, n)
+			t.Equalf(fmtHex(want), fmtHex(r.Big()), "modInverse(%v, %v)", x, n)
+		}
+	}
+
+	r := make(SubtleInt, 2)
+	t.Panics(func() { r.ModInverse(SubtleInt{1, 0}, SubtleInt{2, 1}) }, "must not invert modulo even numbers")
+	t.Panics(func() { r.ModInverse(SubtleInt{1}, SubtleInt{3, 1}) }, "must not invert integers with different lengths")
+}
+
+func (t *TestSubtleIntSuite) TestSetValue() {
+	x := make(SubtleInt, 2)
+	x.setValue([]byte{1, 2, 3})
+	t.Equal(SubtleInt{0x010203, 0}, x, "value not aligned to least significant end")
+
+	t.Panics(func() { x.setValue(make([]byte, 17)) }, "must not set values that are too large")
+}
+
 func (t *TestSubtleIntSuite) TestLess() {
 	for _, a := range t.testValues2 {
 		for _, b := range t.testValues2 {
@@ -0,0 +1,265 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"errors"
+	"fmt"
+)
+
+// agreeCDH validates the public key of the other party and calculates the
+// shared secret with BlindCDH. Static public keys are validated fully and
+// ephemeral public keys partially.
+func (c *SchemeConfig) agreeCDH(own *KeyPair, other *PublicKey, static bool) (*SharedSecret, error) {
+	if static {
+		if err := ValidatePublicKeyFull(other); err != nil {
+			return nil, fmt.Errorf("failed to validate static key: %w", err)
+		}
+	} else if err := ValidatePublicKeyPartial(other); err != nil {
+		return nil, fmt.Errorf("failed to validate ephemeral key: %w", err)
+	}
+	z, err := BlindAgreeCDH(own, other, c.rand())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate shared secret: %w", err)
+	}
+	return z, nil
+}
+
+// agreeUnified validates the public keys of the other party and calculates
+// the shared secret Z = Ze || Zs of the unified model, where Ze is
+// calculated with the ephemeral keys and Zs with the static keys. In the
+// one-pass form, party V passes its static key pair as ownEphemeral and
+// party U the static public key of party V as otherEphemeral.
+func (c *SchemeConfig) agreeUnified(ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) (*SharedSecret, error) {
+	if _, err := checkCurves([]*KeyPair{ownStatic, ownEphemeral}, []*PublicKey{otherStatic, otherEphemeral}); err != nil {
+		return nil, err
+	}
+	if err := validatePeerKeys(otherStatic, otherEphemeral); err != nil {
+		return nil, err
+	}
+	ze, err := BlindAgreeCDH(ownEphemeral, otherEphemeral, c.rand())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate ephemeral shared secret: %w", err)
+	}
+	defer ze.Destroy()
+	zs, err := BlindAgreeCDH(ownStatic, otherStatic, c.rand())
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate static shared secret: %w", err)
+	}
+	defer zs.Destroy()
+	return concatSecrets(ze, zs), nil
+}
+
+// FullUnified implements the full unified model scheme C(2e, 2s, ECC CDH)
+// where both parties contribute a static and an ephemeral key pair. The
+// shared secret is Z = Ze || Zs, where Ze is calculated with the ephemeral
+// keys and Zs with the static keys. See section 6.1.1.2 of SP 800-56A Rev. 3
+// for more details.
+type FullUnified struct {
+	SchemeConfig
+}
+
+// DeriveKey calculates the shared secret with BlindCDH and returns the
+// derived keying material. The static public key of the other party is
+// validated fully and its ephemeral public key partially.
+func (s *FullUnified) DeriveKey(idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agreeUnified(ownStatic, ownEphemeral, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// ConfirmKey is similar to DeriveKey, but additionally returns the key
+// confirmation for the party with the given role. The ephemeral public keys
+// are used as EphemData. See section 6.1.1.3 of SP 800-56A Rev. 3 for more
+// details.
+func (s *FullUnified) ConfirmKey(role Role, idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agreeUnified(ownStatic, ownEphemeral, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+	return s.confirmEphemeral(z, role, idU, idV, ownEphemeral.Public, otherEphemeral)
+}
+
+// EphemeralUnified implements the ephemeral unified model scheme
+// C(2e, 0s, ECC CDH) where both parties only contribute an ephemeral key
+// pair. The scheme does not authenticate the parties and key confirmation is
+// not supported. See section 6.1.2.2 of SP 800-56A Rev. 3 for more details.
+type EphemeralUnified struct {
+	SchemeConfig
+}
+
+// DeriveKey calculates the shared secret with BlindCDH and returns the
+// derived keying material. The ephemeral public key of the other party is
+// validated partially.
+func (s *EphemeralUnified) DeriveKey(idU, idV []byte, ownEphemeral *KeyPair, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agreeCDH(ownEphemeral, otherEphemeral, false)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// OnePassUnified implements the one-pass unified model scheme
+// C(1e, 2s, ECC CDH) where party U (the sender) contributes a static and an
+// ephemeral key pair and party V (the receiver) only a static key pair. The
+// shared secret is Z = Ze || Zs, where Ze is calculated with the ephemeral
+// key of party U and the static key of party V. See section 6.2.1.2 of
+// SP 800-56A Rev. 3 for more details.
+type OnePassUnified struct {
+	SchemeConfig
+}
+
+// DeriveKeyU returns the derived keying material for party U, which
+// sends its ephemeral public key to party V.
+func (s *OnePassUnified) DeriveKeyU(idU, idV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, error) {
+	z, err := s.agreeUnified(ownStatic, ownEphemeral, otherStatic, otherStatic)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// DeriveKeyV returns the derived keying material for party V, which
+// receives the ephemeral public key of party U.
+func (s *OnePassUnified) DeriveKeyV(idU, idV []byte, ownStatic *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agreeUnified(ownStatic, ownStatic, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// ConfirmKeyU is similar to DeriveKeyU, but additionally returns the key
+// confirmation for party U. Like for OnePassMQV, the nonce sent by party V
+// is used as its EphemData. See section 6.2.1.3 of SP 800-56A Rev. 3 for
+// more details.
+func (s *OnePassUnified) ConfirmKeyU(idU, idV, nonceV []byte, ownStatic, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agreeUnified(ownStatic, ownEphemeral, otherStatic, otherStatic)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(ownEphemeral.Public)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyU, idU, idV, ephemU, nonceV)
+}
+
+// ConfirmKeyV is similar to DeriveKeyV, but additionally returns the key
+// confirmation for party V. See ConfirmKeyU for details.
+func (s *OnePassUnified) ConfirmKeyV(idU, idV, nonceV []byte, ownStatic *KeyPair, otherStatic, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	z, err := s.agreeUnified(ownStatic, ownStatic, otherStatic, otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyV, idU, idV, ephemU, nonceV)
+}
+
+// OnePassDH implements the one-pass Diffie-Hellman scheme C(1e, 1s, ECC CDH)
+// where party U (the sender) only contributes an ephemeral key pair and
+// party V (the receiver) only a static key pair. Only party V is
+// authenticated. See section 6.2.2.2 of SP 800-56A Rev. 3 for more details.
+type OnePassDH struct {
+	SchemeConfig
+}
+
+// DeriveKeyU returns the derived keying material for party U, which
+// sends its ephemeral public key to party V.
+func (s *OnePassDH) DeriveKeyU(idU, idV []byte, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, error) {
+	z, err := s.agreeCDH(ownEphemeral, otherStatic, true)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// DeriveKeyV returns the derived keying material for party V, which
+// receives the ephemeral public key of party U.
+func (s *OnePassDH) DeriveKeyV(idU, idV []byte, ownStatic *KeyPair, otherEphemeral *PublicKey) ([]byte, error) {
+	z, err := s.agreeCDH(ownStatic, otherEphemeral, false)
+	if err != nil {
+		return nil, err
+	}
+	defer z.Destroy()
+	macKey, key, err := s.deriveKey(z, idU, idV)
+	WipeBytes(macKey)
+	return key, err
+}
+
+// checkUnilateralV returns an error unless key confirmation is unilateral,
+// since only party V can provide a MacTag in the one-pass Diffie-Hellman
+// scheme.
+func (s *OnePassDH) checkUnilateralV() error {
+	if s.Confirmation != nil && s.Confirmation.Bilateral {
+		return errors.New("only unilateral key confirmation from party V is supported")
+	}
+	return nil
+}
+
+// ConfirmKeyU is similar to DeriveKeyU, but additionally returns the key
+// confirmation for party U, which verifies the MacTag of party V. The nonce
+// sent by party V is used as its EphemData. See section 6.2.2.3 of
+// SP 800-56A Rev. 3 for more details.
+func (s *OnePassDH) ConfirmKeyU(idU, idV, nonceV []byte, ownEphemeral *KeyPair, otherStatic *PublicKey) ([]byte, *Confirmation, error) {
+	if err := s.checkUnilateralV(); err != nil {
+		return nil, nil, err
+	}
+	z, err := s.agreeCDH(ownEphemeral, otherStatic, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(ownEphemeral.Public)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyU, idU, idV, ephemU, nonceV)
+}
+
+// ConfirmKeyV is similar to DeriveKeyV, but additionally returns the key
+// confirmation for party V, which provides the MacTag. See ConfirmKeyU for
+// details.
+func (s *OnePassDH) ConfirmKeyV(idU, idV, nonceV []byte, ownStatic *KeyPair, otherEphemeral *PublicKey) ([]byte, *Confirmation, error) {
+	if err := s.checkUnilateralV(); err != nil {
+		return nil, nil, err
+	}
+	z, err := s.agreeCDH(ownStatic, otherEphemeral, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer z.Destroy()
+
+	ephemU, err := MarshalPublicKey(otherEphemeral)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.confirm(z, PartyV, idU, idV, ephemU, nonceV)
+}
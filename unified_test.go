@@ -0,0 +1,183 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type UnifiedTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	config SchemeConfig
+	idU    []byte
+	idV    []byte
+
+	uStatic    *KeyPair
+	uEphemeral *KeyPair
+	vStatic    *KeyPair
+	vEphemeral *KeyPair
+}
+
+func (s *UnifiedTestSuite) SetupTest() {
+	s.config = SchemeConfig{
+		KDF:         &OneStepKDF{Hash: sha256.New},
+		KeyLen:      32,
+		AlgorithmID: []byte("AES-256"),
+	}
+	s.idU = []byte("alice")
+	s.idV = []byte("bob")
+	s.uStatic = s.generateKeyPair("u static")
+	s.uEphemeral = s.generateKeyPair("u ephemeral")
+	s.vStatic = s.generateKeyPair("v static")
+	s.vEphemeral = s.generateKeyPair("v ephemeral")
+}
+
+// tag returns the MacTag of c and fails the test on errors.
+func (s *UnifiedTestSuite) tag(c *Confirmation, text []byte) []byte {
+	tag, err := c.Tag(text)
+	s.Require().NoError(err, "failed to calculate tag")
+	return tag
+}
+
+func (s *UnifiedTestSuite) generateKeyPair(name string) *KeyPair {
+	kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoErrorf(err, "failed to create key pair %q", name)
+	return kp
+}
+
+func (s *UnifiedTestSuite) confirmation() SchemeConfig {
+	config := s.config
+	config.Confirmation = &KeyConfirmation{
+		MAC:       &HMAC{Hash: sha256.New},
+		MacKeyLen: 32,
+		TagLen:    16,
+	}
+	return config
+}
+
+func (s *UnifiedTestSuite) TestFullUnified() {
+	scheme := &FullUnified{s.config}
+
+	keyU, err := scheme.DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	s.Len(keyU, s.config.KeyLen, "invalid key length")
+
+	keyV, err := scheme.DeriveKey(s.idU, s.idV, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+
+	// Z = Ze || Zs
+	ze, err := AgreeCDH(s.uEphemeral, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to calculate ze")
+	zs, err := AgreeCDH(s.uStatic, s.vStatic.Public)
+	s.Require().NoError(err, "failed to calculate zs")
+	macKey, key, err := s.config.deriveKey(concatSecrets(ze, zs), s.idU, s.idV)
+	s.Require().NoError(err, "failed to derive key")
+	s.Empty(macKey, "unexpected mac key")
+	s.Equal(key, keyU, "key differs from Ze || Zs")
+
+	mqv, err := (&FullMQV{s.config}).DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key with mqv")
+	s.NotEqual(mqv, keyU, "unified key equals mqv key")
+
+	scheme = &FullUnified{s.confirmation()}
+	keyU, confU, err := scheme.ConfirmKey(PartyU, s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to confirm key for u")
+	keyV, confV, err := scheme.ConfirmKey(PartyV, s.idU, s.idV, s.vStatic, s.vEphemeral, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to confirm key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+	s.NoError(confU.Verify(s.tag(confV, nil), nil), "tag of v rejected")
+	s.NoError(confV.Verify(s.tag(confU, nil), nil), "tag of u rejected")
+}
+
+func (s *UnifiedTestSuite) TestEphemeralUnified() {
+	scheme := &EphemeralUnified{s.config}
+
+	keyU, err := scheme.DeriveKey(s.idU, s.idV, s.uEphemeral, s.vEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	keyV, err := scheme.DeriveKey(s.idU, s.idV, s.vEphemeral, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+}
+
+func (s *UnifiedTestSuite) TestOnePassUnified() {
+	scheme := &OnePassUnified{s.config}
+
+	keyU, err := scheme.DeriveKeyU(s.idU, s.idV, s.uStatic, s.uEphemeral, s.vStatic.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	keyV, err := scheme.DeriveKeyV(s.idU, s.idV, s.vStatic, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+
+	nonce := []byte("nonce of v")
+	scheme = &OnePassUnified{s.confirmation()}
+	keyU, confU, err := scheme.ConfirmKeyU(s.idU, s.idV, nonce, s.uStatic, s.uEphemeral, s.vStatic.Public)
+	s.Require().NoError(err, "failed to confirm key for u")
+	keyV, confV, err := scheme.ConfirmKeyV(s.idU, s.idV, nonce, s.vStatic, s.uStatic.Public, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to confirm key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+	s.NoError(confV.Verify(s.tag(confU, nil), nil), "tag of u rejected")
+}
+
+func (s *UnifiedTestSuite) TestOnePassDH() {
+	scheme := &OnePassDH{s.config}
+
+	keyU, err := scheme.DeriveKeyU(s.idU, s.idV, s.uEphemeral, s.vStatic.Public)
+	s.Require().NoError(err, "failed to derive key for u")
+	keyV, err := scheme.DeriveKeyV(s.idU, s.idV, s.vStatic, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+
+	nonce := []byte("nonce of v")
+	scheme = &OnePassDH{s.confirmation()}
+	keyU, confU, err := scheme.ConfirmKeyU(s.idU, s.idV, nonce, s.uEphemeral, s.vStatic.Public)
+	s.Require().NoError(err, "failed to confirm key for u")
+	keyV, confV, err := scheme.ConfirmKeyV(s.idU, s.idV, nonce, s.vStatic, s.uEphemeral.Public)
+	s.Require().NoError(err, "failed to confirm key for v")
+	s.Equal(keyU, keyV, "keys are not equal")
+	s.NoError(confU.Verify(s.tag(confV, nil), nil), "tag of v rejected")
+
+	config := s.confirmation()
+	config.Confirmation.Bilateral = true
+	scheme = &OnePassDH{config}
+	_, _, err = scheme.ConfirmKeyU(s.idU, s.idV, nonce, s.uEphemeral, s.vStatic.Public)
+	s.Error(err, "bilateral key confirmation accepted")
+}
+
+func (s *UnifiedTestSuite) TestInvalidPublicKey() {
+	invalid := &PublicKey{Curve: s.Curve, X: s.vStatic.Public.X, Y: s.vStatic.Public.X}
+
+	_, err := (&FullUnified{s.config}).DeriveKey(s.idU, s.idV, s.uStatic, s.uEphemeral, invalid, s.vEphemeral.Public)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid static key accepted: %v", err)
+	_, err = (&EphemeralUnified{s.config}).DeriveKey(s.idU, s.idV, s.uEphemeral, invalid)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid ephemeral key accepted: %v", err)
+	_, err = (&OnePassUnified{s.config}).DeriveKeyV(s.idU, s.idV, s.vStatic, s.uStatic.Public, invalid)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid ephemeral key accepted: %v", err)
+	_, err = (&OnePassDH{s.config}).DeriveKeyU(s.idU, s.idV, s.uEphemeral, invalid)
+	s.True(errors.Is(err, ErrInvalidPublicKey), "invalid static key accepted: %v", err)
+}
+
+func TestUnifiedP224(t *testing.T) {
+	suite.Run(t, &UnifiedTestSuite{Curve: elliptic.P224()})
+}
+
+func TestUnifiedP256(t *testing.T) {
+	suite.Run(t, &UnifiedTestSuite{Curve: elliptic.P256()})
+}
+
+func TestUnifiedP384(t *testing.T) {
+	suite.Run(t, &UnifiedTestSuite{Curve: elliptic.P384()})
+}
+
+func TestUnifiedP521(t *testing.T) {
+	suite.Run(t, &UnifiedTestSuite{Curve: elliptic.P521()})
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"fmt"
+	"math/big"
+)
+
+// ValidatePublicKeyFull implements the ECC full public-key validation routine
+// of section 5.6.2.3.3 of SP 800-56A Rev. 3. In addition to the checks of
+// ValidatePublicKeyPartial, it verifies that n * Q is the point at infinity,
+// which also rejects all points with a component in a small subgroup if the
+// cofactor is greater than one. Static public keys should be validated with
+// this routine.
+func ValidatePublicKeyFull(pub *PublicKey) error {
+	if err := ValidatePublicKeyPartial(pub); err != nil {
+		return err
+	}
+	curve := canonicalCurve(pub.Curve)
+	x, y := curve.ScalarMult(pub.X, pub.Y, curve.Params().N.Bytes())
+	if !isInfinity(x, y) {
+		return fmt.Errorf("%w: point is not in the subgroup of order n", ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// ValidatePublicKeyPartial implements the ECC partial public-key validation
+// routine of section 5.6.2.3.4 of SP 800-56A Rev. 3. It verifies that the
+// public key is not the point at infinity, that both coordinates are in the
+// range [0, p-1] and that the point is on the curve. For registered curves
+// with a cofactor h > 1, points of small order (h * Q is the point at
+// infinity) are rejected as well. Ephemeral public keys may be validated with
+// this routine.
+func ValidatePublicKeyPartial(pub *PublicKey) error {
+	if pub == nil || pub.Curve == nil || pub.X == nil || pub.Y == nil {
+		return fmt.Errorf("%w: missing key", ErrInvalidPublicKey)
+	}
+	if isInfinity(pub.X, pub.Y) {
+		return fmt.Errorf("%w: point at infinity", ErrInvalidPublicKey)
+	}
+	curve := canonicalCurve(pub.Curve)
+	if err := checkRange(curve, pub.X, pub.Y); err != nil {
+		return err
+	}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return fmt.Errorf("%w: point is not on curve", ErrInvalidPublicKey)
+	}
+	if h, err := cofactor(curve); err == nil && h.Cmp(one) > 0 {
+		x, y := curve.ScalarMult(pub.X, pub.Y, h.Bytes())
+		if isInfinity(x, y) {
+			return fmt.Errorf("%w: point is in a small subgroup", ErrInvalidPublicKey)
+		}
+	}
+	return nil
+}
+
+// checkRange returns an error unless both coordinates are in the range
+// [0, p-1]. The primitives do not validate public keys, but coordinates
+// outside of the field cannot be used by the arithmetic of this package.
+func checkRange(curve elliptic.Curve, x, y *big.Int) error {
+	if x == nil || y == nil {
+		return fmt.Errorf("%w: missing coordinates", ErrInvalidPublicKey)
+	}
+	p := curve.Params().P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return fmt.Errorf("%w: coordinates out of range", ErrInvalidPublicKey)
+	}
+	return nil
+}
+
+// isInfinity returns true if (x, y) is the point at infinity, which is
+// represented by (0, 0) in crypto/elliptic.
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+// validatePeerKeys validates the public keys of the other party. The static
+// public key is validated fully, the ephemeral public key partially.
+func validatePeerKeys(static, ephemeral *PublicKey) error {
+	if err := ValidatePublicKeyFull(static); err != nil {
+		return fmt.Errorf("failed to validate static key: %w", err)
+	}
+	if ephemeral != static {
+		if err := ValidatePublicKeyPartial(ephemeral); err != nil {
+			return fmt.Errorf("failed to validate ephemeral key: %w", err)
+		}
+	}
+	return nil
+}
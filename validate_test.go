@@ -0,0 +1,92 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidateTestSuite struct {
+	Curve elliptic.Curve
+	suite.Suite
+
+	key *KeyPair
+}
+
+func (s *ValidateTestSuite) SetupTest() {
+	var err error
+	s.key, err = GenerateKeyPair(s.Curve, rand.Reader)
+	s.Require().NoError(err, "failed to create key pair")
+}
+
+func (s *ValidateTestSuite) validate(pub *PublicKey, valid bool, msg string) {
+	s.T().Helper()
+	if valid {
+		s.NoError(ValidatePublicKeyPartial(pub), "partial: "+msg)
+		s.NoError(ValidatePublicKeyFull(pub), "full: "+msg)
+	} else {
+		s.Error(ValidatePublicKeyPartial(pub), "partial: "+msg)
+		s.Error(ValidatePublicKeyFull(pub), "full: "+msg)
+	}
+}
+
+func (s *ValidateTestSuite) TestValid() {
+	s.validate(s.key.Public, true, "valid key rejected")
+
+	params := s.Curve.Params()
+	s.validate(&PublicKey{Curve: s.Curve, X: params.Gx, Y: params.Gy}, true, "generator rejected")
+}
+
+func (s *ValidateTestSuite) TestInvalid() {
+	pub := s.key.Public
+	p := s.Curve.Params().P
+
+	s.validate(nil, false, "nil key accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: pub.X}, false, "missing y accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: new(big.Int), Y: new(big.Int)}, false, "infinity accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: pub.X, Y: new(big.Int).Add(pub.Y, one)}, false, "point not on curve accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: new(big.Int).Add(pub.X, p), Y: pub.Y}, false, "x out of range accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: pub.X, Y: new(big.Int).Add(pub.Y, p)}, false, "y out of range accepted")
+	s.validate(&PublicKey{Curve: s.Curve, X: pub.X, Y: new(big.Int).Sub(pub.Y, p)}, false, "negative y accepted")
+}
+
+func (s *ValidateTestSuite) TestScheme() {
+	scheme := &FullMQV{SchemeConfig{KDF: &OneStepKDF{Hash: sha256.New}, KeyLen: 32}}
+	invalid := &PublicKey{Curve: s.Curve, X: s.key.Public.X, Y: new(big.Int).Add(s.key.Public.Y, one)}
+
+	_, err := scheme.DeriveKey(nil, nil, s.key, s.key, invalid, s.key.Public)
+	s.Error(err, "invalid static key accepted")
+
+	_, err = scheme.DeriveKey(nil, nil, s.key, s.key, s.key.Public, invalid)
+	s.Error(err, "invalid ephemeral key accepted")
+
+	onePass := &OnePassMQV{scheme.SchemeConfig}
+	_, err = onePass.DeriveKeyU(nil, nil, s.key, s.key, invalid)
+	s.Error(err, "invalid static key accepted")
+
+	_, err = onePass.DeriveKeyV(nil, nil, s.key, s.key.Public, invalid)
+	s.Error(err, "invalid ephemeral key accepted")
+}
+
+func TestValidateP224(t *testing.T) {
+	suite.Run(t, &ValidateTestSuite{Curve: elliptic.P224()})
+}
+
+func TestValidateP256(t *testing.T) {
+	suite.Run(t, &ValidateTestSuite{Curve: elliptic.P256()})
+}
+
+func TestValidateP384(t *testing.T) {
+	suite.Run(t, &ValidateTestSuite{Curve: elliptic.P384()})
+}
+
+func TestValidateP521(t *testing.T) {
+	suite.Run(t, &ValidateTestSuite{Curve: elliptic.P521()})
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"math/big"
+	"sync"
+)
+
+// WeierstrassCurve is a short Weierstrass curve y^2 = x^3 + a*x + b over a
+// prime field with an arbitrary coefficient a. It implements elliptic.Curve
+// with the constant time arithmetic of this package. The methods of the
+// parameters returned by Params must not be used, since elliptic.CurveParams
+// assumes a = -3.
+type WeierstrassCurve struct {
+	params *elliptic.CurveParams
+	a      *big.Int
+	jac    *jacobianCurve
+}
+
+// NewWeierstrassCurve returns the curve with the domain parameters params
+// and the coefficient a. Use RegisterCurve to make the curve usable with
+// MQV.
+func NewWeierstrassCurve(params *elliptic.CurveParams, a *big.Int) *WeierstrassCurve {
+	a = new(big.Int).Mod(a, params.P)
+	return &WeierstrassCurve{
+		params: params,
+		a:      a,
+		jac:    newJacobianCurve(params.P, a),
+	}
+}
+
+// Params returns the parameters of the curve.
+func (c *WeierstrassCurve) Params() *elliptic.CurveParams {
+	return c.params
+}
+
+// A returns the coefficient a of the curve.
+func (c *WeierstrassCurve) A() *big.Int {
+	return new(big.Int).Set(c.a)
+}
+
+// IsOnCurve reports whether the given (x, y) lies on the curve.
+func (c *WeierstrassCurve) IsOnCurve(x, y *big.Int) bool {
+	p := c.params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+
+	// y^2 = x^3 + a*x + b
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+
+	rhs := new(big.Int).Mul(x, x)
+	rhs.Add(rhs, c.a)
+	rhs.Mul(rhs, x)
+	rhs.Add(rhs, c.params.B)
+	rhs.Mod(rhs, p)
+	return y2.Cmp(rhs) == 0
+}
+
+// Add returns the sum of (x1, y1) and (x2, y2). The point at infinity (0, 0)
+// is returned if a coordinate is not in the range [0, p-1].
+func (c *WeierstrassCurve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	p1 := c.jac.newPoint()
+	defer p1.wipe()
+	p2 := c.jac.newPoint()
+	defer p2.wipe()
+	if !c.jac.setAffine(p1, x1, y1) || !c.jac.setAffine(p2, x2, y2) {
+		return new(big.Int), new(big.Int)
+	}
+	c.jac.add(p1, p1, p2)
+	return c.jac.affine(p1)
+}
+
+// Double returns 2 * (x, y). Like Add, it returns the point at infinity if
+// a coordinate is out of range.
+func (c *WeierstrassCurve) Double(x1, y1 *big.Int) (*big.Int, *big.Int) {
+	p := c.jac.newPoint()
+	defer p.wipe()
+	if !c.jac.setAffine(p, x1, y1) {
+		return new(big.Int), new(big.Int)
+	}
+	c.jac.double(p, p)
+	return c.jac.affine(p)
+}
+
+// ScalarMult returns k * (x, y), where k is a number in big-endian form. It
+// is calculated in constant time. Like Add, it returns the point at infinity
+// if a coordinate is out of range.
+func (c *WeierstrassCurve) ScalarMult(x1, y1 *big.Int, k []byte) (*big.Int, *big.Int) {
+	return c.jac.scalarMult(x1, y1, k, nil)
+}
+
+// ScalarBaseMult returns k * G, where G is the base point of the group and k
+// is a number in big-endian form.
+func (c *WeierstrassCurve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return c.jac.scalarMult(c.params.Gx, c.params.Gy, k, nil)
+}
+
+var (
+	initCurves      sync.Once
+	brainpoolP256r1 *WeierstrassCurve
+	brainpoolP384r1 *WeierstrassCurve
+	brainpoolP512r1 *WeierstrassCurve
+	secp256k1       *WeierstrassCurve
+)
+
+func initWeierstrassCurves() {
+	brainpoolP256r1 = NewWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP256r1",
+		P:       bigFromHex("a9fb57dba1eea9bc3e660a909d838d726e3bf623d52620282013481d1f6e5377"),
+		N:       bigFromHex("a9fb57dba1eea9bc3e660a909d838d718c397aa3b561a6f7901e0e82974856a7"),
+		B:       bigFromHex("26dc5c6ce94a4b44f330b5d9bbd77cbf958416295cf7e1ce6bccdc18ff8c07b6"),
+		Gx:      bigFromHex("8bd2aeb9cb7e57cb2c4b482ffc81b7afb9de27e1e3bd23c23a4453bd9ace3262"),
+		Gy:      bigFromHex("547ef835c3dac4fd97f8461a14611dc9c27745132ded8e545c1d54c72f046997"),
+		BitSize: 256,
+	}, bigFromHex("7d5a0975fc2c3057eef67530417affe7fb8055c126dc5c6ce94a4b44f330b5d9"))
+
+	brainpoolP384r1 = NewWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP384r1",
+		P:       bigFromHex("8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b412b1da197fb71123acd3a729901d1a71874700133107ec53"),
+		N:       bigFromHex("8cb91e82a3386d280f5d6f7e50e641df152f7109ed5456b31f166e6cac0425a7cf3ab6af6b7fc3103b883202e9046565"),
+		B:       bigFromHex("04a8c7dd22ce28268b39b55416f0447c2fb77de107dcd2a62e880ea53eeb62d57cb4390295dbc9943ab78696fa504c11"),
+		Gx:      bigFromHex("1d1c64f068cf45ffa2a63a81b7c13f6b8847a3e77ef14fe3db7fcafe0cbd10e8e826e03436d646aaef87b2e247d4af1e"),
+		Gy:      bigFromHex("8abe1d7520f9c2a45cb1eb8e95cfd55262b70b29feec5864e19c054ff99129280e4646217791811142820341263c5315"),
+		BitSize: 384,
+	}, bigFromHex("7bc382c63d8c150c3c72080ace05afa0c2bea28e4fb22787139165efba91f90f8aa5814a503ad4eb04a8c7dd22ce2826"))
+
+	brainpoolP512r1 = NewWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "brainpoolP512r1",
+		P:       bigFromHex("aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca703308717d4d9b009bc66842aecda12ae6a380e62881ff2f2d82c68528aa6056583a48f3"),
+		N:       bigFromHex("aadd9db8dbe9c48b3fd4e6ae33c9fc07cb308db3b3c9d20ed6639cca70330870553e5c414ca92619418661197fac10471db1d381085ddaddb58796829ca90069"),
+		B:       bigFromHex("3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94cadc083e67984050b75ebae5dd2809bd638016f723"),
+		Gx:      bigFromHex("81aee4bdd82ed9645a21322e9c4c6a9385ed9f70b5d916c1b43b62eef4d0098eff3b1f78e2d0d48d50d1687b93b97d5f7c6d5047406a5e688b352209bcb9f822"),
+		Gy:      bigFromHex("7dde385d566332ecc0eabfa9cf7822fdf209f70024a57b1aa000c55b881f8111b2dcde494a5f485e5bca4bd88a2763aed1ca2b2fa8f0540678cd1e0f3ad80892"),
+		BitSize: 512,
+	}, bigFromHex("7830a3318b603b89e2327145ac234cc594cbdd8d3df91610a83441caea9863bc2ded5d5aa8253aa10a2ef1c98b9ac8b57f1117a72bf2c7b9e7c1ac4d77fc94ca"))
+
+	secp256k1 = NewWeierstrassCurve(&elliptic.CurveParams{
+		Name:    "secp256k1",
+		P:       bigFromHex("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"),
+		N:       bigFromHex("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"),
+		B:       big.NewInt(7),
+		Gx:      bigFromHex("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"),
+		Gy:      bigFromHex("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"),
+		BitSize: 256,
+	}, new(big.Int))
+}
+
+// bigFromHex returns the value of the hexadecimal string s. It panics if s
+// is not a valid number.
+func bigFromHex(s string) *big.Int {
+	v, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex number " + s)
+	}
+	return v
+}
+
+// BrainpoolP256r1 returns the curve brainpoolP256r1 of RFC 5639 with
+// cofactor 1.
+func BrainpoolP256r1() elliptic.Curve {
+	initCurves.Do(initWeierstrassCurves)
+	return brainpoolP256r1
+}
+
+// BrainpoolP384r1 returns the curve brainpoolP384r1 of RFC 5639 with
+// cofactor 1.
+func BrainpoolP384r1() elliptic.Curve {
+	initCurves.Do(initWeierstrassCurves)
+	return brainpoolP384r1
+}
+
+// BrainpoolP512r1 returns the curve brainpoolP512r1 of RFC 5639 with
+// cofactor 1.
+func BrainpoolP512r1() elliptic.Curve {
+	initCurves.Do(initWeierstrassCurves)
+	return brainpoolP512r1
+}
+
+// Secp256k1 returns the curve secp256k1 of SEC 2 with cofactor 1.
+func Secp256k1() elliptic.Curve {
+	initCurves.Do(initWeierstrassCurves)
+	return secp256k1
+}
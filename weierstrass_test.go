@@ -0,0 +1,178 @@
+// Copyright (c) 2017 mgIT GmbH. All rights reserved.
+// Distributed under the Apache License. See LICENSE for details.
+
+package mqv
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// weierstrassVector is a key agreement test vector: Q = d * G and
+// Z = d * QPeer.
+type weierstrassVector struct {
+	d, qx, qy, peerX, peerY, zx, zy string
+}
+
+type WeierstrassTestSuite struct {
+	Curve   elliptic.Curve
+	Vectors []weierstrassVector
+	suite.Suite
+}
+
+func (s *WeierstrassTestSuite) TestVectors() {
+	for i, v := range s.Vectors {
+		d := bigFromHex(v.d).Bytes()
+		x, y := s.Curve.ScalarBaseMult(d)
+		s.Equalf(v.qx, x.Text(16), "public key x differs for vector %d", i)
+		s.Equalf(v.qy, y.Text(16), "public key y differs for vector %d", i)
+		s.Truef(s.Curve.IsOnCurve(x, y), "public key of vector %d not on curve", i)
+
+		if v.peerX == "" {
+			continue
+		}
+		x, y = s.Curve.ScalarMult(bigFromHex(v.peerX), bigFromHex(v.peerY), d)
+		s.Equalf(v.zx, x.Text(16), "shared secret x differs for vector %d", i)
+		s.Equalf(v.zy, y.Text(16), "shared secret y differs for vector %d", i)
+	}
+}
+
+func (s *WeierstrassTestSuite) TestGroupLaw() {
+	params := s.Curve.Params()
+	gx, gy := params.Gx, params.Gy
+	s.True(s.Curve.IsOnCurve(gx, gy), "base point not on curve")
+	s.False(s.Curve.IsOnCurve(gx, new(big.Int).Add(gy, one)), "invalid point on curve")
+	s.False(s.Curve.IsOnCurve(new(big.Int).Add(gx, params.P), gy), "coordinate out of range accepted")
+
+	x2, y2 := s.Curve.ScalarBaseMult([]byte{2})
+	dx, dy := s.Curve.Double(gx, gy)
+	s.Equal(x2.Text(16), dx.Text(16), "double x differs")
+	s.Equal(y2.Text(16), dy.Text(16), "double y differs")
+	ax, ay := s.Curve.Add(gx, gy, gx, gy)
+	s.Equal(x2.Text(16), ax.Text(16), "add of equal points x differs")
+	s.Equal(y2.Text(16), ay.Text(16), "add of equal points y differs")
+
+	x3, y3 := s.Curve.ScalarBaseMult([]byte{3})
+	ax, ay = s.Curve.Add(x2, y2, gx, gy)
+	s.Equal(x3.Text(16), ax.Text(16), "add x differs")
+	s.Equal(y3.Text(16), ay.Text(16), "add y differs")
+
+	ax, ay = s.Curve.Add(gx, gy, gx, new(big.Int).Sub(params.P, gy))
+	s.True(isInfinity(ax, ay), "sum of inverse points is not the point at infinity")
+	ax, ay = s.Curve.Add(new(big.Int), new(big.Int), gx, gy)
+	s.Equal(gx.Text(16), ax.Text(16), "adding the point at infinity changed x")
+	s.Equal(gy.Text(16), ay.Text(16), "adding the point at infinity changed y")
+
+	ax, ay = s.Curve.ScalarBaseMult(params.N.Bytes())
+	s.True(isInfinity(ax, ay), "n * G is not the point at infinity")
+
+	// 2^300 for the 256-bit curves, which has more bytes than p
+	oversized := new(big.Int).Lsh(one, uint(params.BitSize+44))
+	ax, ay = s.Curve.ScalarMult(oversized, oversized, []byte{1})
+	s.True(isInfinity(ax, ay), "multiple of an oversized point is not the point at infinity")
+	ax, ay = s.Curve.Add(gx, gy, oversized, gy)
+	s.True(isInfinity(ax, ay), "sum with an oversized point is not the point at infinity")
+	ax, ay = s.Curve.Double(gx, oversized)
+	s.True(isInfinity(ax, ay), "double of an oversized point is not the point at infinity")
+	ax, ay = s.Curve.Add(params.P, gy, gx, gy)
+	s.True(isInfinity(ax, ay), "sum with x = p is not the point at infinity")
+}
+
+func (s *WeierstrassTestSuite) TestMQV() {
+	kp := func() *KeyPair {
+		kp, err := GenerateKeyPair(s.Curve, rand.Reader)
+		s.Require().NoError(err, "failed to create key pair")
+		return kp
+	}
+	alice, aliceEphemeral, bob, bobEphemeral := kp(), kp(), kp(), kp()
+
+	scheme := &FullMQV{SchemeConfig{KDF: &OneStepKDF{Hash: sha256.New}, KeyLen: 32}}
+	keyU, err := scheme.DeriveKey([]byte("alice"), []byte("bob"), alice, aliceEphemeral, bob.Public, bobEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for alice")
+	keyV, err := scheme.DeriveKey([]byte("alice"), []byte("bob"), bob, bobEphemeral, alice.Public, aliceEphemeral.Public)
+	s.Require().NoError(err, "failed to derive key for bob")
+	s.Equal(keyU, keyV, "derived keys are not equal")
+
+	x, y, err := MQV(alice.Private.D, aliceEphemeral.Private.D, aliceEphemeral.Public.X,
+		bob.Public.X, bob.Public.Y, bobEphemeral.Public.X, bobEphemeral.Public.Y, s.Curve)
+	s.Require().NoError(err, "failed to run mqv")
+	for _, mode := range []BlindMode{0, BlindMultiplicative, BlindPoint} {
+		bx, by, err := BlindMQV(alice.Private.D, aliceEphemeral.Private.D, aliceEphemeral.Public.X,
+			bob.Public.X, bob.Public.Y, bobEphemeral.Public.X, bobEphemeral.Public.Y, s.Curve, rand.Reader, mode)
+		s.Require().NoErrorf(err, "failed to run blind mqv with mode %d", mode)
+		s.Equalf(x.Text(16), bx.Text(16), "blind mqv x differs for mode %d", mode)
+		s.Equalf(y.Text(16), by.Text(16), "blind mqv y differs for mode %d", mode)
+	}
+
+	invalid := &PublicKey{Curve: s.Curve, X: bob.Public.X, Y: new(big.Int).Add(bob.Public.Y, one)}
+	s.Error(ValidatePublicKeyPartial(invalid), "invalid public key accepted")
+	s.NoError(ValidatePublicKeyFull(bob.Public), "valid public key rejected")
+}
+
+// The vectors of the brainpool curves are taken from appendix A of RFC 7027.
+func TestWeierstrassBrainpoolP256r1(t *testing.T) {
+	suite.Run(t, &WeierstrassTestSuite{Curve: BrainpoolP256r1(), Vectors: []weierstrassVector{{
+		d:     "81db1ee100150ff2ea338d708271be38300cb54241d79950f77b063039804f1d",
+		qx:    "44106e913f92bc02a1705d9953a8414db95e1aaa49e81d9e85f929a8e3100be5",
+		qy:    "8ab4846f11caccb73ce49cbdd120f5a900a69fd32c272223f789ef10eb089bdc",
+		peerX: "8d2d688c6cf93e1160ad04cc4429117dc2c41825e1e9fca0addd34e6f1b39f7b",
+		peerY: "990c57520812be512641e47034832106bc7d3e8dd0e4c7f1136d7006547cec6a",
+		zx:    "89afc39d41d3b327814b80940b042590f96556ec91e6ae7939bce31f3a18bf2b",
+		zy:    "49c27868f4eca2179bfd7d59b1e3bf34c1dbde61ae12931648f43e59632504de",
+	}}})
+}
+
+func TestWeierstrassBrainpoolP384r1(t *testing.T) {
+	suite.Run(t, &WeierstrassTestSuite{Curve: BrainpoolP384r1(), Vectors: []weierstrassVector{{
+		d:     "1e20f5e048a5886f1f157c74e91bde2b98c8b52d58e5003d57053fc4b0bd65d6f15eb5d1ee1610df870795143627d042",
+		qx:    "68b665dd91c195800650cdd363c625f4e742e8134667b767b1b476793588f885ab698c852d4a6e77a252d6380fcaf068",
+		qy:    "55bc91a39c9ec01dee36017b7d673a931236d2f1f5c83942d049e3fa20607493e0d038ff2fd30c2ab67d15c85f7faa59",
+		peerX: "4d44326f269a597a5b58bba565da5556ed7fd9a8a9eb76c25f46db69d19dc8ce6ad18e404b15738b2086df37e71d1eb4",
+		peerY: "62d692136de56cbe93bf5fa3188ef58bc8a3a0ec6c1e151a21038a42e9185329b5b275903d192f8d4e1f32fe9cc78c48",
+		zx:    "bd9d3a7ea0b3d519d09d8e48d0785fb744a6b355e6304bc51c229fbbce239bbadf6403715c35d4fb2a5444f575d4f42",
+		zy:    "df213417ebe4d8e40a5f76f66c56470c489a3478d146decf6df0d94bae9e598157290f8756066975f1db34b2324b7bd",
+	}}})
+}
+
+func TestWeierstrassBrainpoolP512r1(t *testing.T) {
+	suite.Run(t, &WeierstrassTestSuite{Curve: BrainpoolP512r1(), Vectors: []weierstrassVector{{
+		d:     "16302ff0dbbb5a8d733dab7141c1b45acbc8715939677f6a56850a38bd87bd59b09e80279609ff333eb9d4c061231fb26f92eeb04982a5f1d1764cad57665422",
+		qx:    "a420517e406aac0acdce90fcd71487718d3b953efd7fbec5f7f27e28c6149999397e91e029e06457db2d3e640668b392c2a7e737a7f0bf04436d11640fd09fd",
+		qy:    "72e6882e8db28aad36237cd25d580db23783961c8dc52dfa2ec138ad472a0fcef3887cf62b623b2a87de5c588301ea3e5fc269b373b60724f5e82a6ad147fde7",
+		peerX: "9d45f66de5d67e2e6db6e93a59ce0bb48106097ff78a081de781cdb31fce8ccbaaea8dd4320c4119f1e9cd437a2eab3731fa9668ab268d871deda55a5473199f",
+		peerY: "2fdc313095bcdd5fb3a91636f07a959c8e86b5636a1e930e8396049cb481961d365cc11453a06c719835475b12cb52fc3c383bce35e27ef194512b71876285fa",
+		zx:    "a7927098655f1f9976fa50a9d566865dc530331846381c87256baf3226244b76d36403c024d7bbf0aa0803eaff405d3d24f11a9b5c0bef679fe1454b21c4cd1f",
+		zy:    "7db71c3def63212841c463e881bdcf055523bd368240e6c3143bd8def8b3b3223b95e0f53082ff5e412f4222537a43df1c6d25729ddb51620a832be6a26680a2",
+	}}})
+}
+
+// The vectors of secp256k1 are well-known multiples of the base point.
+func TestWeierstrassSecp256k1(t *testing.T) {
+	suite.Run(t, &WeierstrassTestSuite{Curve: Secp256k1(), Vectors: []weierstrassVector{
+		{
+			d:  "1",
+			qx: "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			qy: "483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8",
+		},
+		{
+			d:  "2",
+			qx: "c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+			qy: "1ae168fea63dc339a3c58419466ceaeef7f632653266d0e1236431a950cfe52a",
+		},
+		{
+			d:  "3",
+			qx: "f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+			qy: "388f7b0f632de8140fe337e62a37f3566500a99934c2231b6cb9fd7584b8e672",
+		},
+		{
+			d:  "aa5e28d6a97a2479a65527f7290311a3624d4cc0fa1578598ee3c2613bf99522",
+			qx: "34f9460f0e4f08393d192b3c5133a6ba099aa0ad9fd54ebccfacdfa239ff49c6",
+			qy: "b71ea9bd730fd8923f6d25a7a91e7dd7728a960686cb5a901bb419e0f2ca232",
+		},
+	}})
+}